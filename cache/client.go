@@ -19,7 +19,7 @@ type Config struct {
 	// Username to connect to redis
 	Username string `json:"username" koanf:"username"`
 	// Password, must match the password specified in the server configuration
-	Password string `json:"password" koanf:"password"`
+	Password string `json:"password" koanf:"password" sensitive:"true"`
 	// DB to be selected after connecting to the server, 0 uses the default
 	DB int `json:"db" koanf:"db" default:"0"`
 	// Dial timeout for establishing new connections, defaults to 5s
@@ -0,0 +1,266 @@
+package contextx
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// StackKey stores an ordered stack of values of type T in a context; each Push returns a new
+// context whose stack extends the parent's, so the parent context never sees values pushed by its children
+//
+//	var Operations = contextx.NewStackKey[string]()
+//
+//	ctx = Operations.Push(ctx, "createOrg")
+//	ctx = Operations.Push(ctx, "createUser")
+//	Operations.All(ctx) // [createOrg createUser]
+type StackKey[T comparable] struct {
+	key Key[*stackNode[T]]
+}
+
+// stackNode is an immutable element of a stack, shared between a parent context and its children
+type stackNode[T comparable] struct {
+	value  T
+	parent *stackNode[T]
+	depth  int
+}
+
+// NewStackKey returns a new StackKey for values of type T
+func NewStackKey[T comparable](opts ...KeyOption) StackKey[T] {
+	format := func(v any) string {
+		return fmt.Sprintf("%v", v.(*stackNode[T]).all())
+	}
+
+	return StackKey[T]{key: Key[*stackNode[T]]{id: newKeyID(reflect.TypeFor[[]T](), format, opts)}}
+}
+
+// Push returns a copy of ctx with the values pushed onto the stack in order
+func (k StackKey[T]) Push(ctx context.Context, values ...T) context.Context {
+	if len(values) == 0 {
+		return ctx
+	}
+
+	top, _ := k.key.Get(ctx)
+
+	for _, v := range values {
+		n := &stackNode[T]{value: v, parent: top, depth: 1}
+		if top != nil {
+			n.depth = top.depth + 1
+		}
+
+		top = n
+	}
+
+	return k.key.Set(ctx, top)
+}
+
+// Top returns the most recently pushed value and true, or the zero value and false if the stack is empty
+func (k StackKey[T]) Top(ctx context.Context) (T, bool) {
+	top, ok := k.key.Get(ctx)
+	if !ok || top == nil {
+		var zero T
+
+		return zero, false
+	}
+
+	return top.value, true
+}
+
+// All returns the values in the stack from the bottom to the top
+func (k StackKey[T]) All(ctx context.Context) []T {
+	top, _ := k.key.Get(ctx)
+
+	return top.all()
+}
+
+// Contains reports whether v is anywhere in the stack
+func (k StackKey[T]) Contains(ctx context.Context, v T) bool {
+	top, _ := k.key.Get(ctx)
+
+	for n := top; n != nil; n = n.parent {
+		if n.value == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Len returns the number of values in the stack
+func (k StackKey[T]) Len(ctx context.Context) int {
+	top, _ := k.key.Get(ctx)
+	if top == nil {
+		return 0
+	}
+
+	return top.depth
+}
+
+// all returns the values from the bottom of the stack to n
+func (n *stackNode[T]) all() []T {
+	if n == nil {
+		return nil
+	}
+
+	out := make([]T, n.depth)
+
+	for cur := n; cur != nil; cur = cur.parent {
+		out[cur.depth-1] = cur.value
+	}
+
+	return out
+}
+
+// SetKey stores a set of unique values of type T in a context; each Add returns a new context
+// whose set is the union of the parent's and the added values, leaving the parent's set unchanged
+//
+//	var EvaluatedFlags = contextx.NewSetKey[string]()
+//
+//	ctx = EvaluatedFlags.Add(ctx, "new-billing", "beta-ui")
+//	EvaluatedFlags.Contains(ctx, "beta-ui") // true
+type SetKey[T comparable] struct {
+	key Key[*setValue[T]]
+}
+
+// setValue holds the members of a set in insertion order; it is never modified once stored in a context
+type setValue[T comparable] struct {
+	items []T
+	index map[T]struct{}
+}
+
+// NewSetKey returns a new SetKey for values of type T
+func NewSetKey[T comparable](opts ...KeyOption) SetKey[T] {
+	format := func(v any) string {
+		return fmt.Sprintf("%v", v.(*setValue[T]).items)
+	}
+
+	return SetKey[T]{key: Key[*setValue[T]]{id: newKeyID(reflect.TypeFor[[]T](), format, opts)}}
+}
+
+// Add returns a copy of ctx with the values merged into the set
+func (k SetKey[T]) Add(ctx context.Context, values ...T) context.Context {
+	cur, _ := k.key.Get(ctx)
+
+	next := &setValue[T]{index: map[T]struct{}{}}
+	if cur != nil {
+		next.items = slices.Clone(cur.items)
+		next.index = maps.Clone(cur.index)
+	}
+
+	changed := false
+
+	for _, v := range values {
+		if _, ok := next.index[v]; ok {
+			continue
+		}
+
+		next.items = append(next.items, v)
+		next.index[v] = struct{}{}
+		changed = true
+	}
+
+	if !changed {
+		return ctx
+	}
+
+	return k.key.Set(ctx, next)
+}
+
+// All returns the members of the set in the order they were first added
+func (k SetKey[T]) All(ctx context.Context) []T {
+	cur, ok := k.key.Get(ctx)
+	if !ok || cur == nil {
+		return nil
+	}
+
+	return slices.Clone(cur.items)
+}
+
+// Contains reports whether v is a member of the set
+func (k SetKey[T]) Contains(ctx context.Context, v T) bool {
+	cur, ok := k.key.Get(ctx)
+	if !ok || cur == nil {
+		return false
+	}
+
+	_, found := cur.index[v]
+
+	return found
+}
+
+// Len returns the number of members in the set
+func (k SetKey[T]) Len(ctx context.Context) int {
+	cur, ok := k.key.Get(ctx)
+	if !ok || cur == nil {
+		return 0
+	}
+
+	return len(cur.items)
+}
+
+// MapKey stores a map of K to V in a context; each Put or Merge returns a new context whose map
+// is the parent's with the given entries added or replaced, leaving the parent's map unchanged
+//
+//	var Labels = contextx.NewMapKey[string, string]()
+//
+//	ctx = Labels.Put(ctx, "tenant", "acme")
+//	tenant, _ := Labels.Get(ctx, "tenant") // acme
+type MapKey[K comparable, V any] struct {
+	key Key[map[K]V]
+}
+
+// NewMapKey returns a new MapKey for entries of K to V
+func NewMapKey[K comparable, V any](opts ...KeyOption) MapKey[K, V] {
+	return MapKey[K, V]{key: Key[map[K]V]{id: newKeyID(reflect.TypeFor[map[K]V](), nil, opts)}}
+}
+
+// Put returns a copy of ctx with the entry k=v added to the map
+func (k MapKey[K, V]) Put(ctx context.Context, key K, value V) context.Context {
+	return k.Merge(ctx, map[K]V{key: value})
+}
+
+// Merge returns a copy of ctx with the entries of m added to the map, replacing existing entries with the same key
+func (k MapKey[K, V]) Merge(ctx context.Context, m map[K]V) context.Context {
+	if len(m) == 0 {
+		return ctx
+	}
+
+	cur, _ := k.key.Get(ctx)
+
+	next := make(map[K]V, len(cur)+len(m))
+	maps.Copy(next, cur)
+	maps.Copy(next, m)
+
+	return k.key.Set(ctx, next)
+}
+
+// Get returns the value stored for key and true, or the zero value and false if the entry is not present
+func (k MapKey[K, V]) Get(ctx context.Context, key K) (V, bool) {
+	cur, _ := k.key.Get(ctx)
+	v, ok := cur[key]
+
+	return v, ok
+}
+
+// All returns a copy of the map, or nil if nothing has been stored
+func (k MapKey[K, V]) All(ctx context.Context) map[K]V {
+	cur, _ := k.key.Get(ctx)
+
+	return maps.Clone(cur)
+}
+
+// Contains reports whether the map holds an entry for key
+func (k MapKey[K, V]) Contains(ctx context.Context, key K) bool {
+	_, ok := k.Get(ctx, key)
+
+	return ok
+}
+
+// Len returns the number of entries in the map
+func (k MapKey[K, V]) Len(ctx context.Context) int {
+	cur, _ := k.key.Get(ctx)
+
+	return len(cur)
+}
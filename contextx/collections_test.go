@@ -0,0 +1,147 @@
+package contextx
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestStackKey(t *testing.T) {
+	k := NewStackKey[string]()
+
+	base := context.Background()
+
+	if _, ok := k.Top(base); ok {
+		t.Fatal("expected empty stack to have no top")
+	}
+
+	if k.All(base) != nil || k.Len(base) != 0 {
+		t.Fatal("expected empty stack")
+	}
+
+	parent := k.Push(base, "a", "b")
+	childA := k.Push(parent, "c")
+	childB := k.Push(parent, "d")
+
+	if got := k.All(parent); !slices.Equal(got, []string{"a", "b"}) {
+		t.Fatalf("parent stack changed: %v", got)
+	}
+
+	if got := k.All(childA); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Fatalf("unexpected childA stack: %v", got)
+	}
+
+	if got := k.All(childB); !slices.Equal(got, []string{"a", "b", "d"}) {
+		t.Fatalf("unexpected childB stack: %v", got)
+	}
+
+	if top, _ := k.Top(childA); top != "c" {
+		t.Fatalf("expected top c, got %s", top)
+	}
+
+	if !k.Contains(childA, "a") || k.Contains(childA, "d") {
+		t.Fatal("unexpected Contains result")
+	}
+
+	if k.Len(childB) != 3 {
+		t.Fatalf("expected len 3, got %d", k.Len(childB))
+	}
+
+	if k.Push(parent) != parent {
+		t.Fatal("pushing nothing should return the same context")
+	}
+}
+
+func TestSetKey(t *testing.T) {
+	k := NewSetKey[string]()
+
+	parent := k.Add(context.Background(), "flag-a", "flag-b", "flag-a")
+	child := k.Add(parent, "flag-c", "flag-b")
+
+	if got := k.All(parent); !slices.Equal(got, []string{"flag-a", "flag-b"}) {
+		t.Fatalf("unexpected parent set: %v", got)
+	}
+
+	if got := k.All(child); !slices.Equal(got, []string{"flag-a", "flag-b", "flag-c"}) {
+		t.Fatalf("unexpected child set: %v", got)
+	}
+
+	if k.Contains(parent, "flag-c") {
+		t.Fatal("parent set should not contain child values")
+	}
+
+	if !k.Contains(child, "flag-c") || k.Len(child) != 3 {
+		t.Fatal("child set should contain flag-c")
+	}
+
+	if k.Add(child, "flag-a") != child {
+		t.Fatal("adding existing members should return the same context")
+	}
+
+	// modifying the result of All must not change the stored set
+	all := k.All(child)
+	all[0] = "mutated"
+
+	if k.All(child)[0] != "flag-a" {
+		t.Fatal("All should return a copy")
+	}
+}
+
+func TestMapKey(t *testing.T) {
+	k := NewMapKey[string, int]()
+
+	if k.All(context.Background()) != nil || k.Contains(context.Background(), "a") {
+		t.Fatal("expected empty map")
+	}
+
+	parent := k.Merge(context.Background(), map[string]int{"a": 1, "b": 2})
+	child := k.Put(parent, "a", 10)
+	child = k.Put(child, "c", 3)
+
+	if v, _ := k.Get(parent, "a"); v != 1 {
+		t.Fatalf("parent map changed, got a=%d", v)
+	}
+
+	if k.Contains(parent, "c") {
+		t.Fatal("parent should not contain child entry")
+	}
+
+	if v, _ := k.Get(child, "a"); v != 10 {
+		t.Fatalf("expected a=10, got %d", v)
+	}
+
+	if k.Len(child) != 3 {
+		t.Fatalf("expected 3 entries, got %d", k.Len(child))
+	}
+
+	all := k.All(child)
+	all["d"] = 4
+
+	if k.Contains(child, "d") {
+		t.Fatal("All should return a copy")
+	}
+}
+
+func TestCollectionKeysDescribe(t *testing.T) {
+	r := NewRegistry()
+
+	ops := NewStackKey[string](WithName("operations"), WithRegistry(r))
+	flags := NewSetKey[string](WithName("flags"), WithRegistry(r))
+	labels := NewMapKey[string, string](WithName("labels"), WithRegistry(r))
+
+	ctx := ops.Push(context.Background(), "a", "b")
+	ctx = flags.Add(ctx, "x")
+	ctx = labels.Put(ctx, "k", "v")
+
+	d := r.Describe(ctx)
+
+	want := Description{
+		{Name: "operations", Type: "[]string", Value: "[a b]"},
+		{Name: "flags", Type: "[]string", Value: "[x]"},
+		{Name: "labels", Type: "map[string]string", Value: "map[k:v]"},
+	}
+
+	if !slices.Equal(d, want) {
+		t.Fatalf("expected %v, got %v", want, d)
+	}
+}
@@ -0,0 +1,104 @@
+package contextx
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// RedactedValue replaces the value of sensitive keys in a Description
+const RedactedValue = "[REDACTED]"
+
+// DefaultRegistry holds every named key that was not given an explicit registry
+var DefaultRegistry = NewRegistry()
+
+// Registry keeps track of named keys so the values present in a context can be inspected
+type Registry struct {
+	mu   sync.RWMutex
+	keys []*keyID
+}
+
+// NewRegistry returns an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// register adds the key to the registry, keys are described in registration order
+func (r *Registry) register(id *keyID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.keys = append(r.keys, id)
+}
+
+// KeyDescription describes a single registered key present in a context
+type KeyDescription struct {
+	// Name of the key as given to WithName
+	Name string `json:"name"`
+	// Type of the value stored by the key
+	Type string `json:"type"`
+	// Value is the stringified value, or RedactedValue for sensitive keys
+	Value string `json:"value"`
+	// Redacted is true when Value was replaced because the key is sensitive
+	Redacted bool `json:"redacted"`
+}
+
+// Description is the set of registered keys present in a context; it implements slog.LogValuer
+// so a context can be logged safely with slog.Any("context", contextx.Describe(ctx))
+type Description []KeyDescription
+
+// LogValue returns the description as a group of name=value attributes
+func (d Description) LogValue() slog.Value {
+	attrs := make([]slog.Attr, 0, len(d))
+
+	for _, kd := range d {
+		attrs = append(attrs, slog.String(kd.Name, kd.Value))
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+// Describe returns the name, type and value of every key in the registry that is present in ctx
+func (r *Registry) Describe(ctx context.Context) Description {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var d Description
+
+	for _, id := range r.keys {
+		v := ctx.Value(id)
+		if v == nil {
+			continue
+		}
+
+		d = append(d, id.describe(v))
+	}
+
+	return d
+}
+
+// Describe returns the name, type and value of every key in the DefaultRegistry that is present in ctx
+func Describe(ctx context.Context) Description {
+	return DefaultRegistry.Describe(ctx)
+}
+
+// describe builds the KeyDescription for the given value stored by the key
+func (id *keyID) describe(v any) KeyDescription {
+	kd := KeyDescription{
+		Name: id.name,
+		Type: id.typ.String(),
+	}
+
+	switch {
+	case id.sensitive:
+		kd.Value = RedactedValue
+		kd.Redacted = true
+	case id.format != nil:
+		kd.Value = id.format(v)
+	default:
+		kd.Value = fmt.Sprintf("%v", v)
+	}
+
+	return kd
+}
@@ -0,0 +1,98 @@
+package contextx
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestDescribe(t *testing.T) {
+	r := NewRegistry()
+
+	orgKey := NewKey[string](WithName("org_id"), WithRegistry(r))
+	tokenKey := NewKey[string](WithName("token"), WithRegistry(r), Sensitive())
+	countKey := NewKey[int](WithName("count"), WithRegistry(r), WithFormatter(func(v any) string {
+		return strings.Repeat("*", v.(int))
+	}))
+	unsetKey := NewKey[bool](WithName("unset"), WithRegistry(r))
+
+	ctx := orgKey.Set(context.Background(), "org-123")
+	ctx = tokenKey.Set(ctx, "s3cr3t")
+	ctx = countKey.Set(ctx, 3)
+
+	d := r.Describe(ctx)
+
+	want := Description{
+		{Name: "org_id", Type: "string", Value: "org-123"},
+		{Name: "token", Type: "string", Value: RedactedValue, Redacted: true},
+		{Name: "count", Type: "int", Value: "***"},
+	}
+
+	if len(d) != len(want) {
+		t.Fatalf("expected %d descriptions, got %d: %v", len(want), len(d), d)
+	}
+
+	for i := range want {
+		if d[i] != want[i] {
+			t.Fatalf("description %d: expected %+v, got %+v", i, want[i], d[i])
+		}
+	}
+
+	if _, ok := unsetKey.Get(ctx); ok {
+		t.Fatal("unset key should not be present")
+	}
+
+	if orgKey.Name() != "org_id" {
+		t.Fatalf("expected name org_id, got %q", orgKey.Name())
+	}
+}
+
+func TestDescribeDefaultRegistryIgnoresUnnamedKeys(t *testing.T) {
+	named := NewKey[string](WithName("describe_default_test"))
+	unnamed := NewKey[string]()
+
+	ctx := named.Set(context.Background(), "a")
+	ctx = unnamed.Set(ctx, "b")
+
+	var found bool
+
+	for _, kd := range Describe(ctx) {
+		if kd.Value == "b" {
+			t.Fatal("unnamed key should not be described")
+		}
+
+		if kd.Name == "describe_default_test" {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Fatal("expected named key to be described")
+	}
+}
+
+func TestDescriptionLogValue(t *testing.T) {
+	r := NewRegistry()
+
+	userKey := NewKey[string](WithName("user_id"), WithRegistry(r))
+	secretKey := NewKey[string](WithName("password"), WithRegistry(r), Sensitive())
+
+	ctx := userKey.Set(context.Background(), "user-1")
+	ctx = secretKey.Set(ctx, "hunter2")
+
+	var buf bytes.Buffer
+
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("request", slog.Any("ctx", r.Describe(ctx)))
+
+	out := buf.String()
+
+	if !strings.Contains(out, "ctx.user_id=user-1") {
+		t.Fatalf("expected user id in log output, got %q", out)
+	}
+
+	if strings.Contains(out, "hunter2") || !strings.Contains(out, "ctx.password="+RedactedValue) {
+		t.Fatalf("expected password to be redacted, got %q", out)
+	}
+}
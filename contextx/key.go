@@ -1,11 +1,20 @@
 package contextx
 
-import "context"
+import (
+	"context"
+	"reflect"
+)
 
 // keyID is the internal identity of a Key. Each new(keyID) call produces
-// a distinct pointer so two keys over the same type T are always independent
-// (the _ field ensures each allocation is unique)
-type keyID struct{ _ bool }
+// a distinct pointer so two keys over the same type T are always independent;
+// it also carries the descriptive metadata used by Describe
+type keyID struct {
+	name      string
+	typ       reflect.Type
+	sensitive bool
+	format    func(any) string
+	registry  *Registry
+}
 
 // Key stores and retrieves a value of type T in a context; each Key variable
 // is independent (so two Key[string] variables never share or overwrite each other's values)
@@ -13,9 +22,69 @@ type Key[T any] struct {
 	id *keyID
 }
 
-// NewKey returns a new Key for values of type T
-func NewKey[T any]() Key[T] {
-	return Key[T]{id: new(keyID)}
+// KeyOption configures a Key created by NewKey
+type KeyOption func(*keyID)
+
+// WithName names the key and registers it with the DefaultRegistry (unless WithRegistry is also given)
+// so its value is reported by Describe
+func WithName(name string) KeyOption {
+	return func(id *keyID) {
+		id.name = name
+	}
+}
+
+// WithRegistry registers the key with r instead of the DefaultRegistry
+func WithRegistry(r *Registry) KeyOption {
+	return func(id *keyID) {
+		id.registry = r
+	}
+}
+
+// Sensitive marks the key as holding a secret value which is always redacted by Describe
+func Sensitive() KeyOption {
+	return func(id *keyID) {
+		id.sensitive = true
+	}
+}
+
+// WithFormatter sets the function used by Describe to stringify the key's value
+func WithFormatter(fn func(any) string) KeyOption {
+	return func(id *keyID) {
+		id.format = fn
+	}
+}
+
+// NewKey returns a new Key for values of type T; keys given a name with WithName are registered
+// for debugging with Describe
+//
+//	var OrgIDKey = contextx.NewKey[string](contextx.WithName("org_id"))
+func NewKey[T any](opts ...KeyOption) Key[T] {
+	return Key[T]{id: newKeyID(reflect.TypeFor[T](), nil, opts)}
+}
+
+// newKeyID creates the identity for a key of the given type, applying the options and registering
+// named keys; format is the default formatter used when WithFormatter is not given
+func newKeyID(typ reflect.Type, format func(any) string, opts []KeyOption) *keyID {
+	id := &keyID{typ: typ, format: format}
+
+	for _, opt := range opts {
+		opt(id)
+	}
+
+	if id.name != "" {
+		if id.registry == nil {
+			id.registry = DefaultRegistry
+		}
+
+		id.registry.register(id)
+	}
+
+	return id
+}
+
+// Name returns the name given to the key with WithName, or an empty string for unnamed keys
+func (k Key[T]) Name() string {
+	return k.id.name
 }
 
 // Set returns a copy of ctx with v stored in this key
@@ -0,0 +1,206 @@
+package contextx
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrMissingReason is returned when a marker is granted without a reason
+	ErrMissingReason = errors.New("a reason is required to grant a marker")
+	// ErrMissingActor is returned when a marker is granted without a caller identity
+	ErrMissingActor = errors.New("an actor is required to grant a marker")
+	// ErrMissingMarkerName is returned when a marker is created without a name
+	ErrMissingMarkerName = errors.New("a marker requires a name")
+)
+
+// grantsKey holds every marker grant made in a context, most recent on top
+var grantsKey = NewStackKey[*grant]()
+
+// Marker is a privileged capability flag, such as "allow privacy bypass", that replaces empty struct
+// markers checked with Has; unlike a plain value every grant records who set it and why, and can be
+// limited to an expiry or a single use
+//
+//	var PrivacyBypass = contextx.NewMarker("privacy_bypass")
+//
+//	ctx, err := PrivacyBypass.Grant(ctx, "webhook-handler", "sync subscription state", contextx.SingleUse())
+//	...
+//	if _, ok := PrivacyBypass.Use(ctx); ok {
+//		// skip the privacy checks
+//	}
+type Marker struct {
+	id *keyID
+}
+
+// NewMarker returns a new Marker with the given name, it panics if name is empty as markers
+// must always be identifiable in audit logs
+func NewMarker(name string) Marker {
+	if name == "" {
+		panic(ErrMissingMarkerName)
+	}
+
+	return Marker{id: &keyID{name: name, typ: reflect.TypeFor[Marker]()}}
+}
+
+// Name returns the name of the marker
+func (m Marker) Name() string {
+	return m.id.name
+}
+
+// MarkerGrant is the audit record of a marker being set in a context
+type MarkerGrant struct {
+	// Marker is the name of the granted marker
+	Marker string `json:"marker"`
+	// Actor is the identity of the caller that granted the marker
+	Actor string `json:"actor"`
+	// Reason explains why the marker was granted
+	Reason string `json:"reason"`
+	// GrantedAt is when the marker was granted
+	GrantedAt time.Time `json:"granted_at"`
+	// ExpiresAt is when the grant stops being active, zero if it does not expire
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+	// SingleUse is true when the grant is consumed by the first call to Use
+	SingleUse bool `json:"single_use"`
+}
+
+// LogValue returns the grant as a group of attributes for audit logging
+func (g MarkerGrant) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("marker", g.Marker),
+		slog.String("actor", g.Actor),
+		slog.String("reason", g.Reason),
+		slog.Time("granted_at", g.GrantedAt),
+	}
+
+	if !g.ExpiresAt.IsZero() {
+		attrs = append(attrs, slog.Time("expires_at", g.ExpiresAt))
+	}
+
+	if g.SingleUse {
+		attrs = append(attrs, slog.Bool("single_use", true))
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+// GrantOption limits the scope of a marker grant
+type GrantOption func(*MarkerGrant)
+
+// ExpiresAt makes the grant inactive from t onwards
+func ExpiresAt(t time.Time) GrantOption {
+	return func(g *MarkerGrant) {
+		g.ExpiresAt = t
+	}
+}
+
+// ExpiresAfter makes the grant inactive once d has elapsed
+func ExpiresAfter(d time.Duration) GrantOption {
+	return func(g *MarkerGrant) {
+		g.ExpiresAt = g.GrantedAt.Add(d)
+	}
+}
+
+// SingleUse makes the grant inactive after the first call to Use, across every context derived from the granting one
+func SingleUse() GrantOption {
+	return func(g *MarkerGrant) {
+		g.SingleUse = true
+	}
+}
+
+// grant is a MarkerGrant stored in a context along with its marker identity and usage state
+type grant struct {
+	marker *keyID
+	record MarkerGrant
+	used   atomic.Bool
+}
+
+// active reports whether the grant can still be used at the given time
+func (g *grant) active(now time.Time) bool {
+	if !g.record.ExpiresAt.IsZero() && !now.Before(g.record.ExpiresAt) {
+		return false
+	}
+
+	return !g.record.SingleUse || !g.used.Load()
+}
+
+// Grant returns a copy of ctx with the marker set; actor and reason are required and are
+// recorded for auditing along with the scope given by opts
+func (m Marker) Grant(ctx context.Context, actor, reason string, opts ...GrantOption) (context.Context, error) {
+	if actor == "" {
+		return ctx, ErrMissingActor
+	}
+
+	if reason == "" {
+		return ctx, ErrMissingReason
+	}
+
+	g := &grant{
+		marker: m.id,
+		record: MarkerGrant{
+			Marker:    m.id.name,
+			Actor:     actor,
+			Reason:    reason,
+			GrantedAt: time.Now(),
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&g.record)
+	}
+
+	return grantsKey.Push(ctx, g), nil
+}
+
+// find returns the most recent active grant of the marker in ctx
+func (m Marker) find(ctx context.Context) *grant {
+	top, _ := grantsKey.key.Get(ctx)
+	now := time.Now()
+
+	for n := top; n != nil; n = n.parent {
+		if n.value.marker == m.id && n.value.active(now) {
+			return n.value
+		}
+	}
+
+	return nil
+}
+
+// Active reports whether the marker has an active grant in ctx without consuming single use grants
+func (m Marker) Active(ctx context.Context) bool {
+	return m.find(ctx) != nil
+}
+
+// Use returns the most recent active grant of the marker in ctx and true, consuming it if it is single use,
+// or an empty grant and false if the marker is not active
+func (m Marker) Use(ctx context.Context) (MarkerGrant, bool) {
+	for {
+		g := m.find(ctx)
+		if g == nil {
+			return MarkerGrant{}, false
+		}
+
+		// another caller may consume a single use grant between find and here, so look again on failure
+		if !g.record.SingleUse || g.used.CompareAndSwap(false, true) {
+			return g.record, true
+		}
+	}
+}
+
+// ActiveMarkers returns the audit records of every active marker grant in ctx, oldest first
+func ActiveMarkers(ctx context.Context) []MarkerGrant {
+	now := time.Now()
+
+	var out []MarkerGrant
+
+	for _, g := range grantsKey.All(ctx) {
+		if g.active(now) {
+			out = append(out, g.record)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,160 @@
+package contextx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarkerGrantRequiresActorAndReason(t *testing.T) {
+	m := NewMarker("privacy_bypass")
+
+	if _, err := m.Grant(context.Background(), "", "reason"); !errors.Is(err, ErrMissingActor) {
+		t.Fatalf("expected ErrMissingActor, got %v", err)
+	}
+
+	if _, err := m.Grant(context.Background(), "actor", ""); !errors.Is(err, ErrMissingReason) {
+		t.Fatalf("expected ErrMissingReason, got %v", err)
+	}
+}
+
+func TestNewMarkerPanicsWithoutName(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for empty marker name")
+		}
+	}()
+
+	NewMarker("")
+}
+
+func TestMarkerActive(t *testing.T) {
+	bypass := NewMarker("privacy_bypass")
+	other := NewMarker("privacy_bypass")
+
+	ctx, err := bypass.Grant(context.Background(), "svc-webhooks", "sync subscription")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bypass.Active(ctx) {
+		t.Fatal("expected marker to be active")
+	}
+
+	if other.Active(ctx) {
+		t.Fatal("markers with the same name should be independent")
+	}
+
+	if bypass.Active(context.Background()) {
+		t.Fatal("parent context should not have the marker")
+	}
+
+	g, ok := bypass.Use(ctx)
+	if !ok {
+		t.Fatal("expected marker to be usable")
+	}
+
+	if g.Marker != "privacy_bypass" || g.Actor != "svc-webhooks" || g.Reason != "sync subscription" {
+		t.Fatalf("unexpected grant %+v", g)
+	}
+
+	if !bypass.Active(ctx) {
+		t.Fatal("multi use marker should remain active after Use")
+	}
+}
+
+func TestMarkerExpiry(t *testing.T) {
+	m := NewMarker("allow_admin")
+
+	ctx, err := m.Grant(context.Background(), "admin", "migration", ExpiresAt(time.Now().Add(-time.Second)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Active(ctx) {
+		t.Fatal("expired marker should not be active")
+	}
+
+	ctx, err = m.Grant(context.Background(), "admin", "migration", ExpiresAfter(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !m.Active(ctx) {
+		t.Fatal("marker should be active before expiry")
+	}
+}
+
+func TestMarkerSingleUse(t *testing.T) {
+	m := NewMarker("skip_checks")
+
+	ctx, err := m.Grant(context.Background(), "job", "one-off backfill", SingleUse())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	child := context.WithValue(ctx, struct{}{}, "x")
+
+	if !m.Active(child) {
+		t.Fatal("expected marker to be active before use")
+	}
+
+	if _, ok := m.Use(child); !ok {
+		t.Fatal("expected first use to succeed")
+	}
+
+	if _, ok := m.Use(ctx); ok {
+		t.Fatal("single use marker should be consumed across derived contexts")
+	}
+
+	if m.Active(ctx) {
+		t.Fatal("consumed marker should not be active")
+	}
+}
+
+func TestActiveMarkers(t *testing.T) {
+	a := NewMarker("a")
+	b := NewMarker("b")
+	c := NewMarker("c")
+
+	ctx, _ := a.Grant(context.Background(), "alice", "first")
+	ctx, _ = b.Grant(ctx, "bob", "second", ExpiresAt(time.Now().Add(-time.Minute)))
+	ctx, _ = c.Grant(ctx, "carol", "third", SingleUse())
+
+	m := ActiveMarkers(ctx)
+	if len(m) != 2 || m[0].Marker != "a" || m[1].Marker != "c" {
+		t.Fatalf("unexpected active markers %+v", m)
+	}
+
+	c.Use(ctx)
+
+	m = ActiveMarkers(ctx)
+	if len(m) != 1 || m[0].Marker != "a" {
+		t.Fatalf("unexpected active markers after use %+v", m)
+	}
+}
+
+func TestMarkerGrantLogValue(t *testing.T) {
+	m := NewMarker("privacy_bypass")
+
+	ctx, _ := m.Grant(context.Background(), "alice", "support ticket", SingleUse())
+
+	var buf bytes.Buffer
+
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	for _, g := range ActiveMarkers(ctx) {
+		logger.Info("marker active", slog.Any("grant", g))
+	}
+
+	out := buf.String()
+	for _, want := range []string{"grant.marker=privacy_bypass", "grant.actor=alice", `grant.reason="support ticket"`, "grant.single_use=true"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in %q", want, out)
+		}
+	}
+}
@@ -0,0 +1,499 @@
+package contextx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	echo "github.com/theopenlane/echox"
+)
+
+// BaggageHeader is the W3C baggage header used to propagate baggage members
+// See: https://www.w3.org/TR/baggage/
+const BaggageHeader = "baggage"
+
+// Limits applied to propagated values
+const (
+	// DefaultMaxValueLength is the maximum length of a single propagated value when no limit is configured
+	DefaultMaxValueLength = 256
+	// MaxBaggageLength is the maximum size of the baggage header as defined by the W3C specification
+	MaxBaggageLength = 8192
+	// MaxBaggageMembers is the maximum number of baggage list members as defined by the W3C specification
+	MaxBaggageMembers = 64
+)
+
+var (
+	// ErrValueTooLong is returned when a propagated value exceeds the configured maximum length
+	ErrValueTooLong = errors.New("propagated value exceeds maximum length")
+	// ErrInvalidPropagatedValue is returned when a propagated value fails validation or cannot be decoded
+	ErrInvalidPropagatedValue = errors.New("invalid propagated value")
+	// ErrInvalidBaggage is returned when the baggage header exceeds the W3C size or member limits
+	ErrInvalidBaggage = errors.New("invalid baggage header")
+)
+
+// PropagationError describes a propagated value that could not be extracted into the context
+type PropagationError struct {
+	// Name is the header or baggage member name the value was read from
+	Name string
+	// Err is the underlying error
+	Err error
+}
+
+// Error returns the PropagationError in string format
+func (e *PropagationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Name, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *PropagationError) Unwrap() error {
+	return e.Err
+}
+
+// Binding ties a Key to a header or baggage member so its value can be carried across
+// process boundaries; bindings are created with BindHeader, BindBaggage and their string helpers
+type Binding interface {
+	// Name returns the header or baggage member name used by the binding
+	Name() string
+	// inject writes the value from ctx, if present, to the carrier
+	inject(ctx context.Context, c *carrier) error
+	// extract reads the value from the carrier, if present, into ctx
+	extract(ctx context.Context, c *carrier) (context.Context, error)
+}
+
+// BindingOption configures a Binding
+type BindingOption func(*bindingOptions)
+
+type bindingOptions struct {
+	maxLength int
+	validate  func(string) error
+}
+
+// WithMaxLength sets the maximum length of the encoded value; values over the limit are not injected,
+// which Inject reports as an error, and are rejected on extraction
+func WithMaxLength(n int) BindingOption {
+	return func(o *bindingOptions) {
+		o.maxLength = n
+	}
+}
+
+// WithValidator sets a function used to validate the encoded value before it is injected or extracted
+func WithValidator(fn func(string) error) BindingOption {
+	return func(o *bindingOptions) {
+		o.validate = fn
+	}
+}
+
+// binding is the generic implementation of Binding for a Key[T]
+type binding[T any] struct {
+	key     Key[T]
+	name    string
+	baggage bool
+	encode  func(T) (string, error)
+	decode  func(string) (T, error)
+	opts    bindingOptions
+}
+
+// BindHeader binds k to the HTTP header name using encode and decode to convert the value to and from its string form
+func BindHeader[T any](k Key[T], name string, encode func(T) (string, error), decode func(string) (T, error), opts ...BindingOption) Binding {
+	return newBinding(k, http.CanonicalHeaderKey(name), false, encode, decode, opts)
+}
+
+// BindBaggage binds k to the W3C baggage member name using encode and decode to convert the value to and from its string form
+func BindBaggage[T any](k Key[T], name string, encode func(T) (string, error), decode func(string) (T, error), opts ...BindingOption) Binding {
+	return newBinding(k, name, true, encode, decode, opts)
+}
+
+// BindStringHeader binds a string-based key to the HTTP header name
+func BindStringHeader[T ~string](k Key[T], name string, opts ...BindingOption) Binding {
+	return BindHeader(k, name, encodeString[T], decodeString[T], opts...)
+}
+
+// BindStringBaggage binds a string-based key to the W3C baggage member name
+func BindStringBaggage[T ~string](k Key[T], name string, opts ...BindingOption) Binding {
+	return BindBaggage(k, name, encodeString[T], decodeString[T], opts...)
+}
+
+func encodeString[T ~string](v T) (string, error) {
+	return string(v), nil
+}
+
+func decodeString[T ~string](s string) (T, error) {
+	return T(s), nil
+}
+
+func newBinding[T any](k Key[T], name string, baggage bool, encode func(T) (string, error), decode func(string) (T, error), opts []BindingOption) *binding[T] {
+	b := &binding[T]{
+		key:     k,
+		name:    name,
+		baggage: baggage,
+		encode:  encode,
+		decode:  decode,
+		opts:    bindingOptions{maxLength: DefaultMaxValueLength},
+	}
+
+	for _, opt := range opts {
+		opt(&b.opts)
+	}
+
+	return b
+}
+
+// Name returns the header or baggage member name used by the binding
+func (b *binding[T]) Name() string {
+	return b.name
+}
+
+// check enforces the length limit and validator on an encoded value
+func (b *binding[T]) check(s string) error {
+	if b.opts.maxLength > 0 && len(s) > b.opts.maxLength {
+		return &PropagationError{Name: b.name, Err: ErrValueTooLong}
+	}
+
+	if b.opts.validate != nil {
+		if err := b.opts.validate(s); err != nil {
+			return &PropagationError{Name: b.name, Err: fmt.Errorf("%w: %w", ErrInvalidPropagatedValue, err)}
+		}
+	}
+
+	return nil
+}
+
+func (b *binding[T]) inject(ctx context.Context, c *carrier) error {
+	v, ok := b.key.Get(ctx)
+	if !ok {
+		return nil
+	}
+
+	s, err := b.encode(v)
+	if err != nil {
+		return &PropagationError{Name: b.name, Err: fmt.Errorf("%w: %w", ErrInvalidPropagatedValue, err)}
+	}
+
+	if err := b.check(s); err != nil {
+		return err
+	}
+
+	if b.baggage {
+		c.setMember(b.name, s)
+
+		return nil
+	}
+
+	c.header.Set(b.name, s)
+
+	return nil
+}
+
+func (b *binding[T]) extract(ctx context.Context, c *carrier) (context.Context, error) {
+	var (
+		s  string
+		ok bool
+	)
+
+	if b.baggage {
+		s, ok = c.member(b.name)
+	} else {
+		s = c.header.Get(b.name)
+		ok = s != ""
+	}
+
+	if !ok {
+		return ctx, nil
+	}
+
+	if err := b.check(s); err != nil {
+		return ctx, err
+	}
+
+	v, err := b.decode(s)
+	if err != nil {
+		return ctx, &PropagationError{Name: b.name, Err: fmt.Errorf("%w: %w", ErrInvalidPropagatedValue, err)}
+	}
+
+	return b.key.Set(ctx, v), nil
+}
+
+// baggageMember is a single parsed member of the baggage header, properties are kept verbatim
+type baggageMember struct {
+	key        string
+	value      string
+	properties string
+}
+
+// carrier wraps the headers of a request along with its baggage, which is only parsed once a baggage
+// binding needs it
+type carrier struct {
+	header  http.Header
+	members []baggageMember
+	parsed  bool
+	err     error
+	dirty   bool
+}
+
+func newCarrier(h http.Header) *carrier {
+	return &carrier{header: h}
+}
+
+// parseBaggage parses the baggage header on first use. Malformed members are skipped as the W3C
+// specification allows, only a header over the size or member limits is an error, in which case no
+// members are kept
+func (c *carrier) parseBaggage() error {
+	if c.parsed {
+		return c.err
+	}
+
+	c.parsed = true
+
+	raw := strings.Join(c.header.Values(BaggageHeader), ",")
+	if raw == "" {
+		return nil
+	}
+
+	if len(raw) > MaxBaggageLength {
+		c.err = fmt.Errorf("%w: exceeds %d bytes", ErrInvalidBaggage, MaxBaggageLength)
+
+		return c.err
+	}
+
+	for entry := range strings.SplitSeq(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kv, props, _ := strings.Cut(entry, ";")
+
+		k, v, found := strings.Cut(kv, "=")
+		k = strings.TrimSpace(k)
+
+		if !found || k == "" {
+			continue
+		}
+
+		value, err := url.PathUnescape(strings.TrimSpace(v))
+		if err != nil {
+			continue
+		}
+
+		c.members = append(c.members, baggageMember{key: k, value: value, properties: props})
+	}
+
+	if len(c.members) > MaxBaggageMembers {
+		c.members = nil
+		c.err = fmt.Errorf("%w: exceeds %d members", ErrInvalidBaggage, MaxBaggageMembers)
+	}
+
+	return c.err
+}
+
+// member returns the value of the named baggage member
+func (c *carrier) member(name string) (string, bool) {
+	_ = c.parseBaggage()
+
+	for _, m := range c.members {
+		if m.key == name {
+			return m.value, true
+		}
+	}
+
+	return "", false
+}
+
+// setMember replaces or appends the named baggage member; baggage over the limits is unusable and is
+// replaced by the bound members
+func (c *carrier) setMember(name, value string) {
+	_ = c.parseBaggage()
+
+	c.dirty = true
+
+	for i, m := range c.members {
+		if m.key == name {
+			c.members[i] = baggageMember{key: name, value: value}
+
+			return
+		}
+	}
+
+	c.members = append(c.members, baggageMember{key: name, value: value})
+}
+
+// flush writes the baggage members back to the headers if any were changed
+func (c *carrier) flush() error {
+	if !c.dirty {
+		return nil
+	}
+
+	if len(c.members) > MaxBaggageMembers {
+		return fmt.Errorf("%w: exceeds %d members", ErrInvalidBaggage, MaxBaggageMembers)
+	}
+
+	parts := make([]string, 0, len(c.members))
+
+	for _, m := range c.members {
+		part := m.key + "=" + url.PathEscape(m.value)
+		if m.properties != "" {
+			part += ";" + m.properties
+		}
+
+		parts = append(parts, part)
+	}
+
+	value := strings.Join(parts, ",")
+	if len(value) > MaxBaggageLength {
+		return fmt.Errorf("%w: exceeds %d bytes", ErrInvalidBaggage, MaxBaggageLength)
+	}
+
+	c.header.Set(BaggageHeader, value)
+
+	return nil
+}
+
+// Propagator injects bound keys into outgoing HTTP requests and extracts them from incoming ones
+//
+//	var OrgID = contextx.NewKey[string]()
+//
+//	p := contextx.NewPropagator(
+//		contextx.BindStringHeader(OrgID, "X-Organization-ID"),
+//	)
+//
+//	client := &http.Client{Transport: p.Transport(nil)}
+//	e.Use(p.EchoMiddleware())
+type Propagator struct {
+	bindings []Binding
+}
+
+// NewPropagator returns a Propagator for the given bindings
+func NewPropagator(bindings ...Binding) *Propagator {
+	return &Propagator{bindings: bindings}
+}
+
+// Inject writes every bound value present in ctx to h; all bindings are attempted and the
+// errors of values that could not be written are returned joined together
+func (p *Propagator) Inject(ctx context.Context, h http.Header) error {
+	c := newCarrier(h)
+
+	var errs []error
+
+	for _, b := range p.bindings {
+		if err := b.inject(ctx, c); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := c.flush(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Extract reads every bound value present in h into a copy of ctx; values that fail validation
+// are left out of the returned context and their errors are returned joined together. The baggage
+// header is only parsed when a baggage member is bound, and its malformed members are ignored
+func (p *Propagator) Extract(ctx context.Context, h http.Header) (context.Context, error) {
+	var errs []error
+
+	c := newCarrier(h)
+
+	for _, b := range p.bindings {
+		next, err := b.extract(ctx, c)
+		if err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+
+		ctx = next
+	}
+
+	// set only if a baggage binding parsed the header
+	if c.err != nil {
+		errs = append(errs, c.err)
+	}
+
+	return ctx, errors.Join(errs...)
+}
+
+// TransportOption configures the http.RoundTripper returned by Propagator.Transport
+type TransportOption func(*propagatingTransport)
+
+// WithInjectErrorHandler sets a function called with the request and the errors of values that could not
+// be injected; by default they are dropped silently
+func WithInjectErrorHandler(fn func(*http.Request, error)) TransportOption {
+	return func(t *propagatingTransport) {
+		t.onError = fn
+	}
+}
+
+// Transport returns an http.RoundTripper that injects the bound values from the request context
+// before delegating to base, or http.DefaultTransport if base is nil. Values that cannot be injected
+// are left out rather than failing the request, see WithInjectErrorHandler
+func (p *Propagator) Transport(base http.RoundTripper, opts ...TransportOption) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	t := &propagatingTransport{propagator: p, base: base}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+type propagatingTransport struct {
+	propagator *Propagator
+	base       http.RoundTripper
+	onError    func(*http.Request, error)
+}
+
+// RoundTrip clones the request, injects the bound values and calls through to the base RoundTripper
+func (t *propagatingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// a RoundTripper must not modify the request so work on a clone
+	out := req.Clone(req.Context())
+
+	// Inject writes every valid value, so a bad one only loses its own header or baggage member
+	if err := t.propagator.Inject(req.Context(), out.Header); err != nil && t.onError != nil {
+		t.onError(req, err)
+	}
+
+	return t.base.RoundTrip(out)
+}
+
+// Middleware returns net/http middleware that extracts the bound values into the request context,
+// responding with 400 Bad Request if any value is invalid
+func (p *Propagator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, err := p.Extract(r.Context(), r.Header)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// EchoMiddleware returns echo middleware that extracts the bound values into the request context,
+// returning a 400 Bad Request error if any value is invalid
+func (p *Propagator) EchoMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+
+			ctx, err := p.Extract(req.Context(), req.Header)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+
+			c.SetRequest(req.WithContext(ctx))
+
+			return next(c)
+		}
+	}
+}
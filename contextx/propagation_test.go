@@ -0,0 +1,304 @@
+package contextx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	echo "github.com/theopenlane/echox"
+)
+
+func TestPropagatorInjectExtractHeader(t *testing.T) {
+	orgKey := NewKey[string]()
+	p := NewPropagator(BindStringHeader(orgKey, "x-organization-id"))
+
+	h := http.Header{}
+
+	if err := p.Inject(orgKey.Set(context.Background(), "org-123"), h); err != nil {
+		t.Fatalf("unexpected inject error: %v", err)
+	}
+
+	if got := h.Get("X-Organization-Id"); got != "org-123" {
+		t.Fatalf("expected header org-123, got %q", got)
+	}
+
+	ctx, err := p.Extract(context.Background(), h)
+	if err != nil {
+		t.Fatalf("unexpected extract error: %v", err)
+	}
+
+	if v := orgKey.MustGet(ctx); v != "org-123" {
+		t.Fatalf("expected org-123, got %s", v)
+	}
+}
+
+func TestPropagatorInjectSkipsMissingValues(t *testing.T) {
+	orgKey := NewKey[string]()
+	userKey := NewKey[string]()
+	p := NewPropagator(BindStringHeader(orgKey, "X-Org"), BindStringBaggage(userKey, "user"))
+
+	h := http.Header{}
+
+	if err := p.Inject(context.Background(), h); err != nil {
+		t.Fatalf("unexpected inject error: %v", err)
+	}
+
+	if len(h) != 0 {
+		t.Fatalf("expected no headers, got %v", h)
+	}
+}
+
+func TestPropagatorBaggageRoundTrip(t *testing.T) {
+	userKey := NewKey[string]()
+	countKey := NewKey[int]()
+
+	p := NewPropagator(
+		BindStringBaggage(userKey, "user.id"),
+		BindBaggage(countKey, "count", func(v int) (string, error) { return strconv.Itoa(v), nil }, strconv.Atoi),
+	)
+
+	h := http.Header{}
+	h.Set(BaggageHeader, "other=keep;prop=1, user.id=stale")
+
+	ctx := userKey.Set(context.Background(), "user 1,2")
+	ctx = countKey.Set(ctx, 3)
+
+	if err := p.Inject(ctx, h); err != nil {
+		t.Fatalf("unexpected inject error: %v", err)
+	}
+
+	if got := h.Get(BaggageHeader); got != "other=keep;prop=1,user.id=user%201%2C2,count=3" {
+		t.Fatalf("unexpected baggage header %q", got)
+	}
+
+	out, err := p.Extract(context.Background(), h)
+	if err != nil {
+		t.Fatalf("unexpected extract error: %v", err)
+	}
+
+	if v := userKey.MustGet(out); v != "user 1,2" {
+		t.Fatalf("expected decoded user, got %q", v)
+	}
+
+	if v := countKey.MustGet(out); v != 3 {
+		t.Fatalf("expected 3, got %d", v)
+	}
+}
+
+func TestPropagatorValidation(t *testing.T) {
+	errNotNumeric := errors.New("not numeric")
+
+	orgKey := NewKey[string]()
+	traceKey := NewKey[string]()
+
+	p := NewPropagator(
+		BindStringHeader(orgKey, "X-Org", WithMaxLength(4)),
+		BindStringHeader(traceKey, "X-Trace", WithValidator(func(s string) error {
+			if _, err := strconv.Atoi(s); err != nil {
+				return errNotNumeric
+			}
+
+			return nil
+		})),
+	)
+
+	h := http.Header{}
+	h.Set("X-Org", "too-long")
+	h.Set("X-Trace", "abc")
+
+	ctx, err := p.Extract(context.Background(), h)
+	if !errors.Is(err, ErrValueTooLong) {
+		t.Fatalf("expected ErrValueTooLong, got %v", err)
+	}
+
+	if !errors.Is(err, errNotNumeric) || !errors.Is(err, ErrInvalidPropagatedValue) {
+		t.Fatalf("expected validator error, got %v", err)
+	}
+
+	var perr *PropagationError
+	if !errors.As(err, &perr) || perr.Name != "X-Org" {
+		t.Fatalf("expected PropagationError for X-Org, got %v", err)
+	}
+
+	if _, ok := orgKey.Get(ctx); ok {
+		t.Fatal("invalid value should not be extracted")
+	}
+
+	if err := p.Inject(orgKey.Set(context.Background(), "too-long"), http.Header{}); !errors.Is(err, ErrValueTooLong) {
+		t.Fatalf("expected ErrValueTooLong on inject, got %v", err)
+	}
+}
+
+func TestPropagatorMalformedBaggage(t *testing.T) {
+	userKey := NewKey[string]()
+	p := NewPropagator(BindStringBaggage(userKey, "user"))
+
+	h := http.Header{}
+	h.Set(BaggageHeader, "novalue,=empty,bad=%zz, user=u1")
+
+	ctx, err := p.Extract(context.Background(), h)
+	if err != nil {
+		t.Fatalf("malformed members should be skipped, got %v", err)
+	}
+
+	if v := userKey.MustGet(ctx); v != "u1" {
+		t.Fatalf("expected u1, got %q", v)
+	}
+
+	h.Set(BaggageHeader, strings.Repeat("k=v,", MaxBaggageMembers+1))
+
+	if _, err := p.Extract(context.Background(), h); !errors.Is(err, ErrInvalidBaggage) {
+		t.Fatalf("expected ErrInvalidBaggage, got %v", err)
+	}
+}
+
+func TestPropagatorIgnoresBaggageWithoutBinding(t *testing.T) {
+	orgKey := NewKey[string]()
+	p := NewPropagator(BindStringHeader(orgKey, "X-Org"))
+
+	h := http.Header{}
+	h.Set("X-Org", "org-1")
+	h.Set(BaggageHeader, strings.Repeat("k=v,", MaxBaggageMembers+1))
+
+	ctx, err := p.Extract(context.Background(), h)
+	if err != nil {
+		t.Fatalf("baggage should not be parsed without a baggage binding, got %v", err)
+	}
+
+	if v := orgKey.MustGet(ctx); v != "org-1" {
+		t.Fatalf("expected org-1, got %q", v)
+	}
+}
+
+func TestPropagatorTransport(t *testing.T) {
+	orgKey := NewKey[string]()
+	p := NewPropagator(BindStringHeader(orgKey, "X-Org"))
+
+	var received string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("X-Org")
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: p.Transport(nil)}
+
+	req, err := http.NewRequestWithContext(orgKey.Set(context.Background(), "org-1"), http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp.Body.Close()
+
+	if received != "org-1" {
+		t.Fatalf("expected org-1, got %q", received)
+	}
+
+	if req.Header.Get("X-Org") != "" {
+		t.Fatal("transport should not modify the original request")
+	}
+}
+
+func TestPropagatorTransportSkipsInvalidValues(t *testing.T) {
+	orgKey := NewKey[string]()
+	userKey := NewKey[string]()
+	p := NewPropagator(BindStringHeader(orgKey, "X-Org", WithMaxLength(4)), BindStringHeader(userKey, "X-User"))
+
+	var received http.Header
+
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	var reported error
+
+	client := &http.Client{Transport: p.Transport(nil, WithInjectErrorHandler(func(_ *http.Request, err error) { reported = err }))}
+
+	ctx := orgKey.Set(context.Background(), "too-long")
+	ctx = userKey.Set(ctx, "u1")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("an invalid value should not fail the request, got %v", err)
+	}
+
+	resp.Body.Close()
+
+	if !errors.Is(reported, ErrValueTooLong) {
+		t.Fatalf("expected ErrValueTooLong to be reported, got %v", reported)
+	}
+
+	if received.Get("X-Org") != "" || received.Get("X-User") != "u1" {
+		t.Fatalf("expected only X-User to be sent, got %v", received)
+	}
+}
+
+func TestPropagatorMiddleware(t *testing.T) {
+	orgKey := NewKey[string]()
+	p := NewPropagator(BindStringHeader(orgKey, "X-Org", WithMaxLength(8)))
+
+	var got string
+
+	handler := p.Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = orgKey.GetOr(r.Context(), "")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Org", "org-1")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || got != "org-1" {
+		t.Fatalf("expected org-1 with 200, got %q with %d", got, rec.Code)
+	}
+
+	req.Header.Set("X-Org", "much-too-long")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestPropagatorEchoMiddleware(t *testing.T) {
+	orgKey := NewKey[string]()
+	p := NewPropagator(BindStringHeader(orgKey, "X-Org"))
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Org", "org-1")
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	var got string
+
+	err := p.EchoMiddleware()(func(c echo.Context) error {
+		got = orgKey.GetOr(c.Request().Context(), "")
+
+		return nil
+	})(c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got != "org-1" {
+		t.Fatalf("expected org-1, got %q", got)
+	}
+}
@@ -0,0 +1,125 @@
+package contextx
+
+import (
+	"context"
+	"log/slog"
+)
+
+// LogField extracts a single log attribute from a context; fields are created with
+// LogKey, LogString and their redacting variants and passed to NewLogHandler
+type LogField interface {
+	// attr returns the attribute for the value in ctx, and false when the value is not present
+	attr(ctx context.Context) (slog.Attr, bool)
+}
+
+// logKeyField adds the value of a Key[T] as an attribute
+type logKeyField[T any] struct {
+	key    Key[T]
+	name   string
+	redact func(T) string
+}
+
+func (f logKeyField[T]) attr(ctx context.Context) (slog.Attr, bool) {
+	v, ok := f.key.Get(ctx)
+	if !ok {
+		return slog.Attr{}, false
+	}
+
+	switch {
+	case f.redact != nil:
+		return slog.String(f.name, f.redact(v)), true
+	case f.key.id.sensitive:
+		return slog.String(f.name, RedactedValue), true
+	default:
+		return slog.Any(f.name, v), true
+	}
+}
+
+// logStringField adds the value of a typed string stored with WithString as an attribute
+type logStringField[T ~string] struct {
+	name   string
+	redact func(T) string
+}
+
+func (f logStringField[T]) attr(ctx context.Context) (slog.Attr, bool) {
+	v, ok := StringFrom[T](ctx)
+	if !ok {
+		return slog.Attr{}, false
+	}
+
+	if f.redact != nil {
+		return slog.String(f.name, f.redact(v)), true
+	}
+
+	return slog.String(f.name, string(v)), true
+}
+
+// LogKey logs the value of k under the attribute name; keys marked Sensitive are logged as RedactedValue
+func LogKey[T any](k Key[T], name string) LogField {
+	return logKeyField[T]{key: k, name: name}
+}
+
+// LogKeyRedacted logs the value of k under the attribute name after passing it through redact
+func LogKeyRedacted[T any](k Key[T], name string, redact func(T) string) LogField {
+	return logKeyField[T]{key: k, name: name, redact: redact}
+}
+
+// LogString logs the typed string T stored with WithString under the attribute name
+func LogString[T ~string](name string) LogField {
+	return logStringField[T]{name: name}
+}
+
+// LogStringRedacted logs the typed string T stored with WithString under the attribute name after passing it through redact
+func LogStringRedacted[T ~string](name string, redact func(T) string) LogField {
+	return logStringField[T]{name: name, redact: redact}
+}
+
+// LogHandler is a slog.Handler that adds attributes from the context to every record before
+// passing it to the wrapped handler; only records logged with a context, such as with
+// slog.InfoContext, are enriched
+//
+//	logger := slog.New(contextx.NewLogHandler(slog.NewJSONHandler(os.Stdout, nil),
+//		contextx.LogKey(RequestIDKey, "request_id"),
+//		contextx.LogString[OrganizationID]("org_id"),
+//	))
+type LogHandler struct {
+	next   slog.Handler
+	fields []LogField
+}
+
+// NewLogHandler returns a LogHandler wrapping next that adds the given fields to each record
+func NewLogHandler(next slog.Handler, fields ...LogField) *LogHandler {
+	return &LogHandler{next: next, fields: fields}
+}
+
+// Enabled reports whether the wrapped handler handles records at the given level
+func (h *LogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle adds the attributes present in ctx to the record and passes it to the wrapped handler
+func (h *LogHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx == nil {
+		return h.next.Handle(ctx, r)
+	}
+
+	r = r.Clone()
+
+	for _, f := range h.fields {
+		if a, ok := f.attr(ctx); ok {
+			r.AddAttrs(a)
+		}
+	}
+
+	return h.next.Handle(ctx, r)
+}
+
+// WithAttrs returns a new LogHandler whose wrapped handler has the given attributes
+func (h *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &LogHandler{next: h.next.WithAttrs(attrs), fields: h.fields}
+}
+
+// WithGroup returns a new LogHandler whose wrapped handler has the given group
+func (h *LogHandler) WithGroup(name string) slog.Handler {
+	return &LogHandler{next: h.next.WithGroup(name), fields: h.fields}
+}
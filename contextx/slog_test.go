@@ -0,0 +1,105 @@
+package contextx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestLogHandler(t *testing.T) {
+	type OrganizationID string
+
+	type SessionID string
+
+	requestKey := NewKey[string]()
+	userKey := NewKey[int]()
+	tokenKey := NewKey[string](Sensitive())
+	emailKey := NewKey[string]()
+
+	var buf bytes.Buffer
+
+	logger := slog.New(NewLogHandler(slog.NewJSONHandler(&buf, nil),
+		LogKey(requestKey, "request_id"),
+		LogKey(userKey, "user_id"),
+		LogKey(tokenKey, "token"),
+		LogKeyRedacted(emailKey, "email", func(s string) string { return s[:1] + "***" }),
+		LogString[OrganizationID]("org_id"),
+		LogStringRedacted[SessionID]("session", func(SessionID) string { return "hidden" }),
+	))
+
+	ctx := requestKey.Set(context.Background(), "req-1")
+	ctx = userKey.Set(ctx, 42)
+	ctx = tokenKey.Set(ctx, "secret")
+	ctx = emailKey.Set(ctx, "funk@example.com")
+	ctx = WithString(ctx, OrganizationID("org-1"))
+	ctx = WithString(ctx, SessionID("sess-1"))
+
+	logger.InfoContext(ctx, "hello")
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid log output: %v", err)
+	}
+
+	want := map[string]any{
+		"request_id": "req-1",
+		"user_id":    float64(42),
+		"token":      RedactedValue,
+		"email":      "f***",
+		"org_id":     "org-1",
+		"session":    "hidden",
+	}
+
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("expected %s=%v, got %v", k, v, got[k])
+		}
+	}
+}
+
+func TestLogHandlerSkipsMissingValues(t *testing.T) {
+	requestKey := NewKey[string]()
+
+	var buf bytes.Buffer
+
+	logger := slog.New(NewLogHandler(slog.NewJSONHandler(&buf, nil), LogKey(requestKey, "request_id")))
+
+	logger.Info("no context")
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid log output: %v", err)
+	}
+
+	if _, ok := got["request_id"]; ok {
+		t.Fatal("expected request_id to be absent")
+	}
+}
+
+func TestLogHandlerWithAttrsAndGroup(t *testing.T) {
+	requestKey := NewKey[string]()
+
+	var buf bytes.Buffer
+
+	logger := slog.New(NewLogHandler(slog.NewJSONHandler(&buf, nil), LogKey(requestKey, "request_id"))).
+		With("service", "api").
+		WithGroup("req")
+
+	logger.InfoContext(requestKey.Set(context.Background(), "req-1"), "hello")
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid log output: %v", err)
+	}
+
+	if got["service"] != "api" {
+		t.Fatalf("expected service attribute, got %v", got)
+	}
+
+	group, ok := got["req"].(map[string]any)
+	if !ok || group["request_id"] != "req-1" {
+		t.Fatalf("expected request_id in group, got %v", got)
+	}
+}
@@ -0,0 +1,349 @@
+package envparse
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// IndexPlaceholder stands for the index of a slice element in generated documentation, e.g. APP_PROVIDERS_{INDEX}_NAME
+	IndexPlaceholder = "{index}"
+	// KeyPlaceholder stands for the key of a map entry in generated documentation, e.g. APP_PROVIDERS_{KEY}_NAME
+	KeyPlaceholder = "{key}"
+	// MaxSliceLength limits how many elements Discover creates for a slice of structs
+	MaxSliceLength = 1024
+)
+
+// ErrSliceTooLong indicates that a variable names a slice element at or beyond MaxSliceLength
+var ErrSliceTooLong = errors.New("slice index exceeds maximum length")
+
+// Discover creates the slice elements and map entries of spec named by the variables in names so that
+// GatherEnvInfo returns their fields; names may be plain variable names or KEY=value pairs as returned by
+// os.Environ. Slices are grown to the highest index named, e.g. PREFIX_PROVIDERS_2_NAME grows providers
+// to three elements, and map entries are created with the lowercased key, e.g. PREFIX_PROVIDERS_GITHUB_NAME
+// creates the entry github. Map keys are separated from the field name by matching the names of the
+// element fields, so keys may not contain underscores. Nil struct pointers holding a created element are
+// allocated so the element is stored in spec
+func (c Config) Discover(prefix string, spec interface{}, names []string) error {
+	keys := make([]string, 0, len(names))
+
+	for _, name := range names {
+		key, _, _ := strings.Cut(name, "=")
+		keys = append(keys, strings.ToUpper(key))
+	}
+
+	return c.discover(prefix, spec, gatherOptions{names: keys})
+}
+
+// discover grows the collections named by opts and stores the structs created for nil pointers that
+// hold a named variable or path
+func (c Config) discover(prefix string, spec interface{}, opts gatherOptions) error {
+	infos, err := c.gatherEnvInfo(prefix, spec, opts)
+	if err != nil {
+		return err
+	}
+
+	named := make(map[string]bool, len(opts.names)+len(opts.paths))
+
+	for _, name := range opts.names {
+		named[name] = true
+	}
+
+	for _, path := range opts.paths {
+		named[path] = true
+	}
+
+	// collections below nil struct pointers are grown in structs that are only stored by commit
+	commitInfos(slices.DeleteFunc(infos, func(info VarInfo) bool { return !named[info.Key] && !named[info.FullPath] }))
+
+	return nil
+}
+
+// isStructCollection reports whether t is a slice of structs or a map of structs with string keys
+func isStructCollection(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Slice:
+		return isStructElem(t.Elem())
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String && isStructElem(t.Elem())
+	default:
+		return false
+	}
+}
+
+// isStructElem reports whether t is a struct, or pointer to struct, that is not decoded from text
+func isStructElem(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Struct && !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// gatherCollection gathers the fields of every element of a slice or map of structs
+func (c Config) gatherCollection(prefix, path string, f reflect.Value, opts gatherOptions) ([]VarInfo, error) {
+	t := f.Type()
+
+	if opts.placeholders {
+		seg := IndexPlaceholder
+		if t.Kind() == reflect.Map {
+			seg = KeyPlaceholder
+		}
+
+		return c.gatherElement(prefix+"_"+seg, joinPath(path, seg), reflect.New(t.Elem()).Elem(), nil, opts)
+	}
+
+	if opts.names != nil || opts.paths != nil {
+		if err := c.growCollection(prefix, path, f, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	var infos []VarInfo
+
+	if t.Kind() == reflect.Slice {
+		for i := range f.Len() {
+			elemInfos, err := c.gatherElement(prefix+"_"+strconv.Itoa(i), joinPath(path, strconv.Itoa(i)), f.Index(i), nil, opts)
+			if err != nil {
+				return nil, err
+			}
+
+			infos = append(infos, elemInfos...)
+		}
+
+		return infos, nil
+	}
+
+	keys := f.MapKeys()
+	slices.SortFunc(keys, func(a, b reflect.Value) int { return strings.Compare(a.String(), b.String()) })
+
+	for _, key := range keys {
+		// map elements are not addressable, so fields are loaded into a copy that is stored back by commit
+		elem := reflect.New(t.Elem()).Elem()
+		elem.Set(f.MapIndex(key))
+
+		elemInfos, err := c.gatherElement(prefix+"_"+key.String(), joinPath(path, key.String()), elem, func() { f.SetMapIndex(key, elem) }, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		infos = append(infos, elemInfos...)
+	}
+
+	return infos, nil
+}
+
+// gatherElement gathers the fields of a single collection element. A nil struct pointer is replaced by
+// a new struct, which like struct values held in maps is only stored once its fields are loaded, so
+// gathering never modifies the collection
+func (c Config) gatherElement(prefix, path string, elem reflect.Value, commit func(), opts gatherOptions) ([]VarInfo, error) {
+	for elem.Kind() == reflect.Ptr {
+		if elem.IsNil() {
+			elem, commit = newElem(elem, commit)
+		}
+
+		elem = elem.Elem()
+	}
+
+	infos, err := c.gatherPath(prefix, path, elem.Addr().Interface(), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if commit != nil {
+		chainCommit(infos, commit)
+	}
+
+	return infos, nil
+}
+
+// newElem returns a new value for the nil pointer ptr and a commit function storing it in ptr before
+// running outer, if set
+func newElem(ptr reflect.Value, outer func()) (reflect.Value, func()) {
+	elem := reflect.New(ptr.Type().Elem())
+
+	return elem, func() {
+		ptr.Set(elem)
+
+		if outer != nil {
+			outer()
+		}
+	}
+}
+
+// chainCommit makes every variable of a struct stored by commit run commit after its own commit
+func chainCommit(infos []VarInfo, commit func()) {
+	for i := range infos {
+		inner := infos[i].commit
+		if inner == nil {
+			infos[i].commit = commit
+
+			continue
+		}
+
+		infos[i].commit = func() {
+			inner()
+			commit()
+		}
+	}
+}
+
+// collectionEntry is a variable name or file value path below a collection, e.g. PREFIX_PROVIDERS_0_NAME
+// with rest 0_NAME or prefix.providers.0.name with rest 0.name
+type collectionEntry struct {
+	name string
+	rest string
+	path bool
+}
+
+// collectionEntries returns the variable names and file value paths of opts below the collection with
+// the env prefix and FullPath path
+func collectionEntries(prefix, path string, opts gatherOptions) []collectionEntry {
+	var entries []collectionEntry
+
+	base := strings.ToUpper(prefix) + "_"
+
+	for _, name := range opts.names {
+		if rest, ok := strings.CutPrefix(name, base); ok {
+			entries = append(entries, collectionEntry{name: name, rest: rest})
+		}
+	}
+
+	for _, p := range opts.paths {
+		if rest, ok := strings.CutPrefix(p, path+"."); ok {
+			entries = append(entries, collectionEntry{name: p, rest: rest, path: true})
+		}
+	}
+
+	return entries
+}
+
+// growCollection creates the elements of a slice or map of structs named by the variables and file
+// value paths of opts
+func (c Config) growCollection(prefix, path string, f reflect.Value, opts gatherOptions) error {
+	t := f.Type()
+	entries := collectionEntries(prefix, path, opts)
+
+	if t.Kind() == reflect.Slice {
+		n := f.Len()
+
+		for _, e := range entries {
+			sep := "_"
+			if e.path {
+				sep = "."
+			}
+
+			seg, _, _ := strings.Cut(e.rest, sep)
+
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 {
+				continue
+			}
+
+			if i >= MaxSliceLength {
+				return &ParseError{Key: e.name, FullPath: path, Err: fmt.Errorf("%w: %d", ErrSliceTooLong, i)}
+			}
+
+			n = max(n, i+1)
+		}
+
+		if n > f.Len() {
+			grown := reflect.MakeSlice(t, n, n)
+			reflect.Copy(grown, f)
+			f.Set(grown)
+		}
+
+		return nil
+	}
+
+	fields := c.fieldKeys(t.Elem())
+
+	for _, e := range entries {
+		// file keys are separated by dots, so unlike variable names they may contain underscores
+		key, _, _ := strings.Cut(strings.ToLower(e.rest), ".")
+		if !e.path {
+			key = mapKey(e.rest, fields)
+		}
+
+		if key == "" || hasMapKey(f, key) {
+			continue
+		}
+
+		if f.IsNil() {
+			f.Set(reflect.MakeMap(t))
+		}
+
+		f.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), reflect.New(t.Elem()).Elem())
+	}
+
+	return nil
+}
+
+// fieldKeys returns the uppercased FieldTagName names of the fields of a struct type
+func (c Config) fieldKeys(t reflect.Type) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var keys []string
+
+	for i := range t.NumField() {
+		ftype := t.Field(i)
+
+		name := c.getFieldName(ftype)
+		if name == c.Skipper || !ftype.IsExported() {
+			continue
+		}
+
+		if ftype.Anonymous && isStructElem(ftype.Type) {
+			keys = append(keys, c.fieldKeys(ftype.Type)...)
+
+			continue
+		}
+
+		keys = append(keys, strings.ToUpper(name))
+	}
+
+	return keys
+}
+
+// mapKey returns the lowercased map key in front of a field name in rest, e.g. github for GITHUB_NAME
+func mapKey(rest string, fields []string) string {
+	key, field, ok := strings.Cut(rest, "_")
+	if !ok || key == "" {
+		return ""
+	}
+
+	for _, name := range fields {
+		if field == name || strings.HasPrefix(field, name+"_") {
+			return strings.ToLower(key)
+		}
+	}
+
+	return ""
+}
+
+// hasMapKey reports whether the map has a key equal to key ignoring case
+func hasMapKey(m reflect.Value, key string) bool {
+	for _, k := range m.MapKeys() {
+		if strings.EqualFold(k.String(), key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// commitInfos stores fields of structs held by value in maps back into their maps and stores the structs
+// created for nil pointers; only functions loading values into the spec call it
+func commitInfos(infos []VarInfo) {
+	for _, info := range infos {
+		if info.commit != nil {
+			info.commit()
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package envparse_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theopenlane/utils/envparse"
+)
+
+type provider struct {
+	Name    string `json:"name" koanf:"name" required:"true" description:"provider name"`
+	Token   string `json:"token" koanf:"token" sensitive:"true"`
+	Retries int    `json:"retries" koanf:"retries" default:"3"`
+}
+
+type collectionSpec struct {
+	Upstreams []provider           `json:"upstreams" koanf:"upstreams"`
+	Providers map[string]provider  `json:"providers" koanf:"providers"`
+	Hooks     map[string]*provider `json:"hooks" koanf:"hooks"`
+}
+
+func TestGatherEnvInfoCollections(t *testing.T) {
+	spec := collectionSpec{
+		Upstreams: []provider{{Name: "a"}, {Name: "b"}},
+		Providers: map[string]provider{"github": {Name: "gh"}},
+		Hooks:     map[string]*provider{"slack": nil},
+	}
+
+	infos, err := testConfig().GatherEnvInfo("APP", &spec)
+	require.NoError(t, err)
+
+	var keys, paths []string
+	for _, info := range infos {
+		keys = append(keys, info.Key)
+		paths = append(paths, info.FullPath)
+	}
+
+	assert.Equal(t, []string{
+		"APP_UPSTREAMS_0_NAME", "APP_UPSTREAMS_0_TOKEN", "APP_UPSTREAMS_0_RETRIES",
+		"APP_UPSTREAMS_1_NAME", "APP_UPSTREAMS_1_TOKEN", "APP_UPSTREAMS_1_RETRIES",
+		"APP_PROVIDERS_GITHUB_NAME", "APP_PROVIDERS_GITHUB_TOKEN", "APP_PROVIDERS_GITHUB_RETRIES",
+		"APP_HOOKS_SLACK_NAME", "APP_HOOKS_SLACK_TOKEN", "APP_HOOKS_SLACK_RETRIES",
+	}, keys)
+	assert.Equal(t, "app.upstreams.1.name", paths[3])
+	assert.Equal(t, "app.providers.github.name", paths[6])
+	assert.Equal(t, "gh", infos[6].Value())
+	assert.Nil(t, spec.Hooks["slack"], "the spec is not modified")
+}
+
+func TestLoadCollections(t *testing.T) {
+	env := map[string]string{
+		"APP_UPSTREAMS_1_NAME":       "second",
+		"APP_UPSTREAMS_0_NAME":       "first",
+		"APP_PROVIDERS_GITHUB_NAME":  "gh",
+		"APP_PROVIDERS_GITHUB_TOKEN": "secret",
+		"APP_PROVIDERS_GITLAB_NAME":  "gl",
+		"APP_HOOKS_SLACK_NAME":       "slack",
+		"APP_PROVIDERS_NOFIELD":      "ignored",
+	}
+
+	names := make([]string, 0, len(env))
+	for k, v := range env {
+		names = append(names, k+"="+v)
+	}
+
+	spec := collectionSpec{Providers: map[string]provider{"github": {Retries: 1}}}
+
+	require.NoError(t, testConfig().Discover("APP", &spec, names))
+	require.NoError(t, testConfig().ApplyDefaults("APP", &spec))
+	require.NoError(t, testConfig().LoadFrom("APP", &spec, envparse.MapLookup(env)))
+
+	assert.Equal(t, []provider{{Name: "first", Retries: 3}, {Name: "second", Retries: 3}}, spec.Upstreams)
+	assert.Equal(t, map[string]provider{
+		"github": {Name: "gh", Token: "secret", Retries: 1},
+		"gitlab": {Name: "gl", Retries: 3},
+	}, spec.Providers)
+	assert.Equal(t, &provider{Name: "slack", Retries: 3}, spec.Hooks["slack"])
+
+	require.NoError(t, testConfig().Validate("APP", &spec))
+
+	spec.Upstreams = append(spec.Upstreams, provider{})
+
+	var verrs envparse.ValidationErrors
+	require.ErrorAs(t, testConfig().Validate("APP", &spec), &verrs)
+	assert.Equal(t, "APP_UPSTREAMS_2_NAME", verrs[0].Key)
+
+	err := testConfig().Discover("APP", &collectionSpec{}, []string{"APP_UPSTREAMS_5000_NAME"})
+	assert.ErrorIs(t, err, envparse.ErrSliceTooLong)
+}
+
+func TestLoadCollectionsBelowNilPointer(t *testing.T) {
+	type inner struct {
+		Providers []provider `json:"providers" koanf:"providers"`
+	}
+
+	spec := struct {
+		Inner *inner `json:"inner" koanf:"inner"`
+		Other *inner `json:"other" koanf:"other"`
+	}{}
+
+	env := map[string]string{"APP_INNER_PROVIDERS_0_NAME": "gh"}
+
+	names := make([]string, 0, len(env))
+	for k := range env {
+		names = append(names, k)
+	}
+
+	require.NoError(t, testConfig().Discover("APP", &spec, names))
+	require.NoError(t, testConfig().LoadFrom("APP", &spec, envparse.MapLookup(env)))
+
+	require.NotNil(t, spec.Inner)
+	require.Len(t, spec.Inner.Providers, 1)
+	assert.Equal(t, "gh", spec.Inner.Providers[0].Name)
+	assert.Nil(t, spec.Other, "pointers without named elements are left alone")
+}
+
+func TestLoaderCollections(t *testing.T) {
+	file := writeFile(t, "config.yaml", `
+upstreams:
+  - name: from-file
+    retries: 7
+providers:
+  GitHub:
+    name: gh
+`)
+
+	loader := envparse.Loader{
+		Config:  testConfig(),
+		Prefix:  "APP",
+		Files:   []string{file},
+		Lookup:  envparse.MapLookup(map[string]string{"APP_UPSTREAMS_1_NAME": "from-env"}),
+		Environ: []string{"APP_UPSTREAMS_1_NAME=from-env"},
+	}
+
+	spec := collectionSpec{}
+
+	report, err := loader.Load(&spec)
+	require.NoError(t, err)
+
+	assert.Equal(t, []provider{{Name: "from-file", Retries: 7}, {Name: "from-env", Retries: 3}}, spec.Upstreams)
+	assert.Equal(t, map[string]provider{"github": {Name: "gh", Retries: 3}}, spec.Providers)
+
+	p, _ := report.Get("app.upstreams.1.name")
+	assert.Equal(t, envparse.OriginEnv, p.Origin)
+
+	p, _ = report.Get("app.providers.github.name")
+	assert.Equal(t, envparse.OriginFile, p.Origin)
+}
+
+func TestLoaderCollectionsKoanfTags(t *testing.T) {
+	file := writeFile(t, "config.yaml", `
+providers:
+  - name: gh
+hooks:
+  on_call:
+    name: pager
+`)
+
+	spec := struct {
+		Providers []provider          `json:"upstreams" koanf:"providers"`
+		Hooks     map[string]provider `json:"webhooks" koanf:"hooks"`
+	}{}
+
+	loader := envparse.Loader{
+		Config: testConfig(),
+		Prefix: "APP",
+		Files:  []string{file},
+		Lookup: envparse.MapLookup(nil),
+	}
+
+	report, err := loader.Load(&spec)
+	require.NoError(t, err)
+
+	assert.Equal(t, []provider{{Name: "gh", Retries: 3}}, spec.Providers)
+	assert.Equal(t, map[string]provider{"on_call": {Name: "pager", Retries: 3}}, spec.Hooks)
+
+	p, ok := report.Get("app.providers.0.name")
+	require.True(t, ok)
+	assert.Equal(t, "APP_UPSTREAMS_0_NAME", p.Key)
+	assert.Equal(t, envparse.OriginFile, p.Origin)
+}
+
+func TestGenerateCollections(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(t, testConfig().Generate(&buf, envparse.FormatMarkdown, "APP", &collectionSpec{}))
+	assert.Contains(t, buf.String(), "| `APP_UPSTREAMS_{INDEX}_NAME` | string |  | yes | provider name |")
+	assert.Contains(t, buf.String(), "| `APP_PROVIDERS_{KEY}_RETRIES` | int | `3` |  |  |")
+
+	buf.Reset()
+
+	require.NoError(t, testConfig().Generate(&buf, envparse.FormatJSONSchema, "APP", &collectionSpec{}))
+
+	var schema map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &schema))
+
+	props := schema["properties"].(map[string]any)
+
+	upstreams := props["upstreams"].(map[string]any)
+	assert.Equal(t, "array", upstreams["type"])
+	assert.Equal(t, []any{"name"}, upstreams["items"].(map[string]any)["required"])
+
+	providers := props["providers"].(map[string]any)
+	assert.Equal(t, "object", providers["type"])
+	assert.Contains(t, providers["additionalProperties"].(map[string]any)["properties"], "retries")
+	assert.NotContains(t, providers, "properties")
+}
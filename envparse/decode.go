@@ -0,0 +1,233 @@
+package envparse
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	durationType        = reflect.TypeFor[time.Duration]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// decodeValue converts value to the type of field and stores it in the field
+//
+// Supported are all scalar kinds, time.Duration, encoding.TextUnmarshaler implementations,
+// pointers to any supported type, slices as comma-separated values and maps as comma-separated k=v pairs
+func decodeValue(field reflect.Value, value string) error {
+	typ := field.Type()
+
+	if typ.Kind() == reflect.Ptr {
+		// decode into a new value so a failure leaves the field untouched
+		ptr := reflect.New(typ.Elem())
+		if err := decodeValue(ptr.Elem(), value); err != nil {
+			return err
+		}
+
+		field.Set(ptr)
+
+		return nil
+	}
+
+	if field.CanAddr() && field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if typ == durationType {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return err
+			}
+
+			field.SetInt(int64(d))
+
+			return nil
+		}
+
+		n, err := strconv.ParseInt(value, 0, typ.Bits())
+		if err != nil {
+			return err
+		}
+
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(value, 0, typ.Bits())
+		if err != nil {
+			return err
+		}
+
+		field.SetUint(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+
+		field.SetBool(b)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, typ.Bits())
+		if err != nil {
+			return err
+		}
+
+		field.SetFloat(f)
+	case reflect.Complex64, reflect.Complex128:
+		c, err := strconv.ParseComplex(value, typ.Bits())
+		if err != nil {
+			return err
+		}
+
+		field.SetComplex(c)
+	case reflect.Slice:
+		return decodeSlice(field, value)
+	case reflect.Map:
+		return decodeMap(field, value)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedType, typ)
+	}
+
+	return nil
+}
+
+// decodeSlice decodes comma-separated values into a slice, []byte is set from the raw value
+func decodeSlice(field reflect.Value, value string) error {
+	typ := field.Type()
+
+	if typ.Elem().Kind() == reflect.Uint8 {
+		field.SetBytes([]byte(value))
+
+		return nil
+	}
+
+	parts := splitList(value)
+	slice := reflect.MakeSlice(typ, len(parts), len(parts))
+
+	for i, part := range parts {
+		if err := decodeValue(slice.Index(i), part); err != nil {
+			return err
+		}
+	}
+
+	field.Set(slice)
+
+	return nil
+}
+
+// decodeMap decodes comma-separated k=v pairs into a map
+func decodeMap(field reflect.Value, value string) error {
+	typ := field.Type()
+	m := reflect.MakeMap(typ)
+
+	for _, pair := range splitList(value) {
+		k, v, found := strings.Cut(pair, "=")
+		if !found {
+			return fmt.Errorf("%w: expected key=value, got %q", ErrInvalidMapItem, pair)
+		}
+
+		key := reflect.New(typ.Key()).Elem()
+		if err := decodeValue(key, strings.TrimSpace(k)); err != nil {
+			return err
+		}
+
+		elem := reflect.New(typ.Elem()).Elem()
+		if err := decodeValue(elem, strings.TrimSpace(v)); err != nil {
+			return err
+		}
+
+		m.SetMapIndex(key, elem)
+	}
+
+	field.Set(m)
+
+	return nil
+}
+
+// splitList splits a comma-separated value, trimming whitespace around each item
+func splitList(value string) []string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+
+	parts := strings.Split(value, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+
+	return parts
+}
+
+// encodeValue returns the string form of field in the format accepted by decodeValue; nil pointers
+// are returned as an empty string
+func encodeValue(field reflect.Value) string {
+	for field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return ""
+		}
+
+		field = field.Elem()
+	}
+
+	if field.Type() == durationType {
+		return time.Duration(field.Int()).String()
+	}
+
+	if tm, ok := textMarshaler(field); ok {
+		b, err := tm.MarshalText()
+		if err == nil {
+			return string(b)
+		}
+	}
+
+	switch field.Kind() {
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.Uint8 {
+			return string(field.Bytes())
+		}
+
+		parts := make([]string, field.Len())
+		for i := range parts {
+			parts[i] = encodeValue(field.Index(i))
+		}
+
+		return strings.Join(parts, ",")
+	case reflect.Map:
+		parts := make([]string, 0, field.Len())
+
+		iter := field.MapRange()
+		for iter.Next() {
+			parts = append(parts, encodeValue(iter.Key())+"="+encodeValue(iter.Value()))
+		}
+
+		slices.Sort(parts)
+
+		return strings.Join(parts, ",")
+	default:
+		return fmt.Sprint(field.Interface())
+	}
+}
+
+// textMarshaler returns the encoding.TextMarshaler implementation of field, if any
+func textMarshaler(field reflect.Value) (encoding.TextMarshaler, bool) {
+	if field.CanInterface() {
+		if tm, ok := field.Interface().(encoding.TextMarshaler); ok {
+			return tm, true
+		}
+	}
+
+	if field.CanAddr() && field.Addr().CanInterface() {
+		if tm, ok := field.Addr().Interface().(encoding.TextMarshaler); ok {
+			return tm, true
+		}
+	}
+
+	return nil, false
+}
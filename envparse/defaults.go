@@ -0,0 +1,61 @@
+package envparse
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Struct tags used when applying defaults
+const (
+	// DefaultTag holds the default value of a field, decoded the same way as an environment variable
+	DefaultTag = "default"
+	// NoDefaultTag opts a field out of ApplyDefaults when set to true, e.g. `nodefault:"true"`
+	NoDefaultTag = "nodefault"
+)
+
+// ErrInvalidDefault indicates that the default tag of a field cannot be decoded into the field type
+var ErrInvalidDefault = errors.New("invalid default value")
+
+// ApplyDefaults sets every zero-valued field gathered by GatherEnvInfo from its default tag; nested
+// structs and nil struct pointers are walked the same way as GatherEnvInfo (nil pointers are allocated)
+// so their fields receive defaults too. Fields with an empty default tag or `nodefault:"true"` are skipped.
+//
+// ApplyDefaults should run before Load so that explicitly set zero values, such as ENABLED=false,
+// are not replaced by their default
+func (c Config) ApplyDefaults(prefix string, spec interface{}) error {
+	infos, err := c.GatherEnvInfo(prefix, spec)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, info := range infos {
+		if err := applyDefault(info); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	commitInfos(infos)
+
+	return errors.Join(errs...)
+}
+
+// applyDefault sets the field from its default tag when the field is zero and defaults are not disabled
+func applyDefault(info VarInfo) error {
+	def := info.Default()
+	if def == "" || !info.Field.IsZero() {
+		return nil
+	}
+
+	if skip, _ := strconv.ParseBool(info.Tags.Get(NoDefaultTag)); skip {
+		return nil
+	}
+
+	if err := decodeValue(info.Field, def); err != nil {
+		return &ParseError{Key: info.Key, FullPath: info.FullPath, Err: fmt.Errorf("%w: %w", ErrInvalidDefault, err)}
+	}
+
+	return nil
+}
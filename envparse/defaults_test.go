@@ -0,0 +1,77 @@
+package envparse_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theopenlane/utils/cache"
+	"github.com/theopenlane/utils/envparse"
+)
+
+type defaultsNested struct {
+	Host string `json:"host" koanf:"host" default:"localhost"`
+}
+
+type defaultsSpec struct {
+	Name     string            `json:"name" koanf:"name" default:"app"`
+	Port     int               `json:"port" koanf:"port" default:"8080"`
+	Timeout  time.Duration     `json:"timeout" koanf:"timeout" default:"5s"`
+	Tags     []string          `json:"tags" koanf:"tags" default:"a,b"`
+	Labels   map[string]string `json:"labels" koanf:"labels" default:"env=dev"`
+	Ratio    *float64          `json:"ratio" koanf:"ratio" default:"0.5"`
+	Optional string            `json:"optional" koanf:"optional" default:"unused" nodefault:"true"`
+	Empty    string            `json:"empty" koanf:"empty" default:""`
+	Nested   defaultsNested    `json:"nested" koanf:"nested"`
+	NestPtr  *defaultsNested   `json:"nestptr" koanf:"nestptr"`
+}
+
+func TestApplyDefaults(t *testing.T) {
+	spec := defaultsSpec{Port: 9090}
+
+	require.NoError(t, testConfig().ApplyDefaults("APP", &spec))
+
+	assert.Equal(t, "app", spec.Name)
+	assert.Equal(t, 9090, spec.Port, "non-zero values must not be replaced")
+	assert.Equal(t, 5*time.Second, spec.Timeout)
+	assert.Equal(t, []string{"a", "b"}, spec.Tags)
+	assert.Equal(t, map[string]string{"env": "dev"}, spec.Labels)
+	require.NotNil(t, spec.Ratio)
+	assert.InDelta(t, 0.5, *spec.Ratio, 0)
+	assert.Empty(t, spec.Optional)
+	assert.Empty(t, spec.Empty)
+	assert.Equal(t, "localhost", spec.Nested.Host)
+	require.NotNil(t, spec.NestPtr)
+	assert.Equal(t, "localhost", spec.NestPtr.Host)
+}
+
+func TestApplyDefaultsThenLoad(t *testing.T) {
+	spec := struct {
+		Cache cache.Config `json:"cache" koanf:"cache"`
+	}{}
+
+	env := envparse.MapLookup(map[string]string{"CORE_CACHE_ENABLED": "false"})
+
+	require.NoError(t, testConfig().ApplyDefaults("CORE", &spec))
+	require.NoError(t, testConfig().LoadFrom("CORE", &spec, env))
+
+	assert.False(t, spec.Cache.Enabled)
+	assert.Equal(t, "localhost:6379", spec.Cache.Address)
+	assert.Equal(t, 5*time.Second, spec.Cache.DialTimeout)
+	assert.Equal(t, 3, spec.Cache.MaxRetries)
+}
+
+func TestApplyDefaultsInvalid(t *testing.T) {
+	spec := struct {
+		Port int `json:"port" koanf:"port" default:"eighty"`
+	}{}
+
+	err := testConfig().ApplyDefaults("APP", &spec)
+	require.ErrorIs(t, err, envparse.ErrInvalidDefault)
+
+	var perr *envparse.ParseError
+	require.ErrorAs(t, err, &perr)
+	assert.Equal(t, "APP_PORT", perr.Key)
+}
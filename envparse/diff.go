@@ -0,0 +1,200 @@
+package envparse
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+
+	"github.com/theopenlane/utils/cli/tables"
+)
+
+// DiffKind describes how a variable differs between two configurations
+type DiffKind string
+
+const (
+	// DiffAdded is used for variables only set in the second configuration
+	DiffAdded DiffKind = "added"
+	// DiffRemoved is used for variables only set in the first configuration
+	DiffRemoved DiffKind = "removed"
+	// DiffChanged is used for variables set to different values
+	DiffChanged DiffKind = "changed"
+)
+
+// Difference is a single variable that differs between two configurations
+type Difference struct {
+	// Key is the environment variable name
+	Key string `json:"key"`
+	// FullPath is the path of the field within the configuration
+	FullPath string `json:"path"`
+	// Kind tells whether the variable was added, removed or changed
+	Kind DiffKind `json:"kind"`
+	// Old is the value in the first configuration, redacted for sensitive fields
+	Old string `json:"old,omitempty"`
+	// New is the value in the second configuration, redacted for sensitive fields
+	New string `json:"new,omitempty"`
+}
+
+// Diff lists the variables that differ between two configurations, see Config.Diff and Config.DiffEnv
+type Diff []Difference
+
+// diffValue is the value of one variable in one of the compared configurations
+type diffValue struct {
+	info  VarInfo
+	value string
+	set   bool
+}
+
+// Diff compares two loaded configurations of the same type and reports every variable, by Key, that
+// is only set in a (removed), only set in b (added) or set to different values (changed). Values of
+// sensitive fields are redacted; a changed secret is reported without revealing either value
+func (c Config) Diff(prefix string, a, b interface{}) (Diff, error) {
+	before, err := c.specValues(prefix, a)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := c.specValues(prefix, b)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffValues(before, after), nil
+}
+
+// DiffEnv compares two sets of environment variables for the configuration spec describes, e.g. the
+// staging and production environments of a service, without decoding or resolving them. Only the
+// variables of spec are compared, including slice elements and map entries named by either set;
+// spec is only used for its type and is not modified
+func (c Config) DiffEnv(prefix string, spec interface{}, a, b map[string]string) (Diff, error) {
+	s := reflect.ValueOf(spec)
+	if s.Kind() != reflect.Ptr || s.Elem().Kind() != reflect.Struct {
+		return nil, ErrInvalidSpecification
+	}
+
+	names := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		names = append(names, k)
+	}
+
+	for k := range b {
+		names = append(names, k)
+	}
+
+	fresh := reflect.New(s.Elem().Type()).Interface()
+
+	infos, err := c.gatherEnvInfo(prefix, fresh, gatherOptions{names: names})
+	if err != nil {
+		return nil, err
+	}
+
+	envValues := func(env map[string]string) []diffValue {
+		values := make([]diffValue, len(infos))
+
+		for i, info := range infos {
+			v, ok := env[info.Key]
+			values[i] = diffValue{info: info, value: v, set: ok}
+		}
+
+		return values
+	}
+
+	return diffValues(envValues(a), envValues(b)), nil
+}
+
+// HasDrift reports whether any variable differs
+func (d Diff) HasDrift() bool {
+	return len(d) > 0
+}
+
+// WriteTable writes the differences to w as a table
+func (d Diff) WriteTable(w io.Writer) error {
+	table := tables.NewTableWriter(w, "Key", "Path", "Change", "Old", "New")
+
+	for _, diff := range d {
+		if err := table.AddRow(diff.Key, diff.FullPath, diff.Kind, diff.Old, diff.New); err != nil {
+			return err
+		}
+	}
+
+	return table.Render()
+}
+
+// WriteJSON writes the differences to w as a JSON document with a drift flag, for use in CI checks
+func (d Diff) WriteJSON(w io.Writer) error {
+	if d == nil {
+		d = Diff{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(struct {
+		Drift       bool `json:"drift"`
+		Differences Diff `json:"differences"`
+	}{Drift: d.HasDrift(), Differences: d})
+}
+
+// specValues returns the current value of every variable of a loaded spec; zero values count as unset
+func (c Config) specValues(prefix string, spec interface{}) ([]diffValue, error) {
+	infos, err := c.GatherEnvInfo(prefix, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]diffValue, len(infos))
+
+	for i, info := range infos {
+		values[i] = diffValue{info: info, value: info.Value(), set: !isEmpty(info.Field)}
+	}
+
+	return values, nil
+}
+
+// diffValues compares two sets of values by Key, in the order of after followed by removed variables
+func diffValues(before, after []diffValue) Diff {
+	old := make(map[string]diffValue, len(before))
+	for _, v := range before {
+		old[v.info.Key] = v
+	}
+
+	seen := make(map[string]bool, len(after))
+
+	var diff Diff
+
+	for _, v := range after {
+		seen[v.info.Key] = true
+
+		prev := old[v.info.Key]
+
+		d := Difference{Key: v.info.Key, FullPath: v.info.FullPath}
+
+		switch {
+		case v.set && !prev.set:
+			d.Kind = DiffAdded
+		case !v.set && prev.set:
+			d.Kind = DiffRemoved
+		case v.set && prev.value != v.value:
+			d.Kind = DiffChanged
+		default:
+			continue
+		}
+
+		if prev.set {
+			d.Old = redact(v.info, prev.value)
+		}
+
+		if v.set {
+			d.New = redact(v.info, v.value)
+		}
+
+		diff = append(diff, d)
+	}
+
+	for _, v := range before {
+		if !seen[v.info.Key] && v.set {
+			diff = append(diff, Difference{Key: v.info.Key, FullPath: v.info.FullPath, Kind: DiffRemoved, Old: redact(v.info, v.value)})
+		}
+	}
+
+	return diff
+}
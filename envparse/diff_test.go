@@ -0,0 +1,89 @@
+package envparse_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theopenlane/utils/envparse"
+)
+
+type diffSpec struct {
+	Level    string              `json:"level" koanf:"level"`
+	Workers  int                 `json:"workers" koanf:"workers"`
+	Password string              `json:"password" koanf:"password" sensitive:"true"`
+	Debug    bool                `json:"debug" koanf:"debug"`
+	Hooks    map[string]provider `json:"hooks" koanf:"hooks"`
+}
+
+func TestDiff(t *testing.T) {
+	staging := diffSpec{Level: "debug", Workers: 2, Password: "a", Debug: true, Hooks: map[string]provider{"slack": {Name: "s"}}}
+	production := diffSpec{Level: "warn", Workers: 2, Password: "b", Hooks: map[string]provider{"pager": {Name: "p"}}}
+
+	diff, err := testConfig().Diff("APP", &staging, &production)
+	require.NoError(t, err)
+
+	assert.Equal(t, envparse.Diff{
+		{Key: "APP_LEVEL", FullPath: "app.level", Kind: envparse.DiffChanged, Old: "debug", New: "warn"},
+		{Key: "APP_PASSWORD", FullPath: "app.password", Kind: envparse.DiffChanged, Old: envparse.RedactedValue, New: envparse.RedactedValue},
+		{Key: "APP_DEBUG", FullPath: "app.debug", Kind: envparse.DiffRemoved, Old: "true"},
+		{Key: "APP_HOOKS_PAGER_NAME", FullPath: "app.hooks.pager.name", Kind: envparse.DiffAdded, New: "p"},
+		{Key: "APP_HOOKS_SLACK_NAME", FullPath: "app.hooks.slack.name", Kind: envparse.DiffRemoved, Old: "s"},
+	}, diff)
+	assert.True(t, diff.HasDrift())
+
+	same, err := testConfig().Diff("APP", &staging, &staging)
+	require.NoError(t, err)
+	assert.False(t, same.HasDrift())
+}
+
+func TestDiffEnv(t *testing.T) {
+	staging := map[string]string{"APP_LEVEL": "debug", "APP_PASSWORD": "a", "APP_HOOKS_SLACK_NAME": "s", "OTHER": "x"}
+	production := map[string]string{"APP_LEVEL": "debug", "APP_PASSWORD": "b", "APP_WORKERS": "4"}
+
+	spec := diffSpec{}
+
+	diff, err := testConfig().DiffEnv("APP", &spec, staging, production)
+	require.NoError(t, err)
+
+	assert.Equal(t, envparse.Diff{
+		{Key: "APP_WORKERS", FullPath: "app.workers", Kind: envparse.DiffAdded, New: "4"},
+		{Key: "APP_PASSWORD", FullPath: "app.password", Kind: envparse.DiffChanged, Old: envparse.RedactedValue, New: envparse.RedactedValue},
+		{Key: "APP_HOOKS_SLACK_NAME", FullPath: "app.hooks.slack.name", Kind: envparse.DiffRemoved, Old: "s"},
+	}, diff)
+	assert.Nil(t, spec.Hooks, "spec is not modified")
+
+	_, err = testConfig().DiffEnv("APP", spec, staging, production)
+	assert.ErrorIs(t, err, envparse.ErrInvalidSpecification)
+}
+
+func TestDiffOutput(t *testing.T) {
+	diff := envparse.Diff{{Key: "APP_LEVEL", FullPath: "app.level", Kind: envparse.DiffChanged, Old: "debug", New: "warn"}}
+
+	var buf bytes.Buffer
+
+	require.NoError(t, diff.WriteTable(&buf))
+	assert.Contains(t, buf.String(), "APP_LEVEL")
+	assert.Contains(t, buf.String(), "changed")
+
+	buf.Reset()
+
+	require.NoError(t, diff.WriteJSON(&buf))
+
+	var out struct {
+		Drift       bool          `json:"drift"`
+		Differences envparse.Diff `json:"differences"`
+	}
+
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+	assert.True(t, out.Drift)
+	assert.Equal(t, diff, out.Differences)
+
+	buf.Reset()
+
+	require.NoError(t, envparse.Diff(nil).WriteJSON(&buf))
+	assert.JSONEq(t, `{"drift": false, "differences": []}`, buf.String())
+}
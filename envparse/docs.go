@@ -0,0 +1,353 @@
+package envparse
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/stoewer/go-strcase"
+)
+
+// Struct tags used to document a field
+const (
+	// DescriptionTag holds the description of a field
+	DescriptionTag = "description"
+	// DocTag is an alternative to DescriptionTag
+	DocTag = "doc"
+	// RequiredTag marks a field as required when set to true
+	RequiredTag = "required"
+)
+
+// Output formats supported by Generate
+const (
+	FormatMarkdown   = "markdown"
+	FormatDotEnv     = "dotenv"
+	FormatJSONSchema = "jsonschema"
+)
+
+// ErrUnknownFormat indicates that Generate was called with an unsupported output format
+var ErrUnknownFormat = errors.New("unknown output format")
+
+// Description returns the description of the variable from its description or doc tag
+func (v VarInfo) Description() string {
+	if d := v.Tags.Get(DescriptionTag); d != "" {
+		return d
+	}
+
+	return v.Tags.Get(DocTag)
+}
+
+// Default returns the value of the default tag of the variable
+func (v VarInfo) Default() string {
+	return v.Tags.Get(DefaultTag)
+}
+
+// Required reports whether the variable is marked as required
+func (v VarInfo) Required() bool {
+	required, _ := strconv.ParseBool(v.Tags.Get(RequiredTag))
+
+	return required
+}
+
+// WriteMarkdown writes a markdown reference table of the variables to w
+func WriteMarkdown(w io.Writer, infos []VarInfo) error {
+	var sb strings.Builder
+
+	sb.WriteString("| Key | Type | Default | Required | Description |\n")
+	sb.WriteString("|-----|------|---------|----------|-------------|\n")
+
+	for _, info := range infos {
+		def := ""
+		if d := redact(info, info.Default()); d != "" {
+			def = "`" + markdownEscape(d) + "`"
+		}
+
+		required := ""
+		if info.Required() {
+			required = "yes"
+		}
+
+		fmt.Fprintf(&sb, "| `%s` | %s | %s | %s | %s |\n",
+			info.Key, markdownEscape(info.Type.String()), def, required, markdownEscape(info.Description()))
+	}
+
+	_, err := io.WriteString(w, sb.String())
+
+	return err
+}
+
+// markdownEscape escapes characters that would break a markdown table cell
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+// WriteDotEnv writes a commented .env.example file of the variables to w; each variable is
+// preceded by its description and type, and set to its default value
+func WriteDotEnv(w io.Writer, infos []VarInfo) error {
+	var sb strings.Builder
+
+	for i, info := range infos {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+
+		if d := info.Description(); d != "" {
+			fmt.Fprintf(&sb, "# %s\n", strings.ReplaceAll(d, "\n", "\n# "))
+		}
+
+		fmt.Fprintf(&sb, "# type: %s", info.Type)
+
+		if info.Required() {
+			sb.WriteString(" (required)")
+		}
+
+		if info.Sensitive() {
+			sb.WriteString(" (sensitive)")
+		}
+
+		value := info.Default()
+		if info.Sensitive() {
+			// never write secrets into a template, even defaults
+			value = ""
+		}
+
+		fmt.Fprintf(&sb, "\n%s=%s\n", info.Key, dotEnvQuote(value))
+	}
+
+	_, err := io.WriteString(w, sb.String())
+
+	return err
+}
+
+// dotEnvQuote quotes a value if it contains characters that a .env parser would otherwise interpret
+func dotEnvQuote(s string) string {
+	if strings.ContainsAny(s, " #\"'\t\n") {
+		return strconv.Quote(s)
+	}
+
+	return s
+}
+
+// JSONSchema returns a JSON Schema document describing the configuration; properties are nested
+// by the FullPath of each variable below the root derived from prefix, and keyed by the FieldTagName names
+func JSONSchema(prefix string, infos []VarInfo) ([]byte, error) {
+	root := schemaObject()
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+
+	base := pathRoot(prefix)
+
+	for _, info := range infos {
+		path := info.FullPath
+		if base != "" {
+			path = strings.TrimPrefix(path, base+".")
+		}
+
+		segments := strings.Split(path, ".")
+		parent := root
+
+		for _, seg := range segments[:len(segments)-1] {
+			parent = schemaChild(parent, seg)
+		}
+
+		last := segments[len(segments)-1]
+		parent["properties"].(map[string]any)[last] = fieldSchema(info)
+
+		if info.Required() {
+			required, _ := parent["required"].([]string)
+			parent["required"] = append(required, last)
+		}
+	}
+
+	return json.MarshalIndent(root, "", "  ")
+}
+
+// pathRoot returns the FullPath prefix GatherEnvInfo produces for the given env prefix
+func pathRoot(prefix string) string {
+	if prefix == "" {
+		return ""
+	}
+
+	return strcase.LowerCamelCase(strings.ReplaceAll(prefix, "_", "."))
+}
+
+// schemaChild returns the object schema of seg below parent, turning parent into an array or a map
+// for the placeholders of slices and maps of structs
+func schemaChild(parent map[string]any, seg string) map[string]any {
+	key := "properties"
+
+	switch seg {
+	case IndexPlaceholder:
+		parent["type"], key = "array", "items"
+	case KeyPlaceholder:
+		key = "additionalProperties"
+	default:
+		child, ok := parent["properties"].(map[string]any)[seg].(map[string]any)
+		if !ok {
+			child = schemaObject()
+			parent["properties"].(map[string]any)[seg] = child
+		}
+
+		return child
+	}
+
+	delete(parent, "properties")
+
+	child, ok := parent[key].(map[string]any)
+	if !ok {
+		child = schemaObject()
+		parent[key] = child
+	}
+
+	return child
+}
+
+func schemaObject() map[string]any {
+	return map[string]any{"type": "object", "properties": map[string]any{}}
+}
+
+// fieldSchema returns the schema of a single variable including its description and default
+func fieldSchema(info VarInfo) map[string]any {
+	s := typeSchema(info.Type)
+
+	if d := info.Description(); d != "" {
+		s["description"] = d
+	}
+
+	s["x-env"] = info.Key
+
+	if info.Sensitive() {
+		s["writeOnly"] = true
+	} else if def := info.Default(); def != "" {
+		s["default"] = schemaDefault(info.Type, def, s["type"])
+	}
+
+	return s
+}
+
+// typeSchema maps a Go type to its JSON Schema representation
+func typeSchema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == durationType {
+		return map[string]any{"type": "string", "pattern": `^([-+]?([0-9]*(\.[0-9]*)?[a-z]+)+|0)$`}
+	}
+
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return map[string]any{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string"}
+		}
+
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	default:
+		return map[string]any{"type": "string"}
+	}
+}
+
+// schemaDefault converts a default tag to a value of the schema type, falling back to the raw string
+func schemaDefault(t reflect.Type, def string, schemaType any) any {
+	if schemaType == "string" {
+		return def
+	}
+
+	v := reflect.New(t).Elem()
+	if err := decodeValue(v, def); err != nil {
+		return def
+	}
+
+	return v.Interface()
+}
+
+// Generate writes the documentation of spec in the given format to w; slices and maps of structs are
+// documented once with IndexPlaceholder or KeyPlaceholder in place of the index or key
+func (c Config) Generate(w io.Writer, format, prefix string, spec interface{}) error {
+	infos, err := c.gatherEnvInfo(prefix, spec, gatherOptions{placeholders: true})
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case FormatMarkdown:
+		return WriteMarkdown(w, infos)
+	case FormatDotEnv:
+		return WriteDotEnv(w, infos)
+	case FormatJSONSchema:
+		out, err := JSONSchema(prefix, infos)
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(w, "%s\n", out)
+
+		return err
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+}
+
+// GenerateMain is the body of a small generator command for use with go generate; it parses -format
+// and -out from args and writes the documentation of spec. Errors, including invalid flags, are
+// returned so the command decides how to exit
+//
+//	//go:build ignore
+//
+//	package main
+//
+//	func main() {
+//		cfg := envparse.Config{FieldTagName: "koanf", Skipper: "-"}
+//		if err := cfg.GenerateMain("CORE", &config.Config{}, os.Args[1:]); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+//
+// and is run with
+//
+//	//go:generate go run gen_config.go -format markdown -out CONFIG.md
+func (c Config) GenerateMain(prefix string, spec interface{}, args []string) (err error) {
+	fs := flag.NewFlagSet("envparse", flag.ContinueOnError)
+	format := fs.String("format", FormatMarkdown, "output format: markdown, dotenv or jsonschema")
+	out := fs.String("out", "", "output file, defaults to stdout")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	w := io.Writer(os.Stdout)
+
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			err = errors.Join(err, f.Close())
+		}()
+
+		w = f
+	}
+
+	return c.Generate(w, *format, prefix, spec)
+}
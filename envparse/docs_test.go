@@ -0,0 +1,121 @@
+package envparse_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theopenlane/utils/envparse"
+)
+
+type docsServer struct {
+	Listen  string        `json:"listen" koanf:"listen" default:":8080" description:"address to listen on"`
+	Timeout time.Duration `json:"timeout" koanf:"timeout" default:"5s" doc:"request timeout"`
+}
+
+type docsSpec struct {
+	Name   string            `json:"name" koanf:"name" required:"true" description:"name of the service | app"`
+	Debug  bool              `json:"debug" koanf:"debug" default:"false"`
+	Hosts  []string          `json:"hosts" koanf:"hosts" default:"a,b"`
+	Port   int               `json:"port" koanf:"port" default:"80"`
+	Labels map[string]string `json:"labels" koanf:"labels"`
+	Server docsServer        `json:"server" koanf:"server"`
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(t, testConfig().Generate(&buf, envparse.FormatMarkdown, "APP", &docsSpec{}))
+
+	expected := "| Key | Type | Default | Required | Description |\n" +
+		"|-----|------|---------|----------|-------------|\n" +
+		"| `APP_NAME` | string |  | yes | name of the service \\| app |\n" +
+		"| `APP_DEBUG` | bool | `false` |  |  |\n" +
+		"| `APP_HOSTS` | []string | `a,b` |  |  |\n" +
+		"| `APP_PORT` | int | `80` |  |  |\n" +
+		"| `APP_LABELS` | map[string]string |  |  |  |\n" +
+		"| `APP_SERVER_LISTEN` | string | `:8080` |  | address to listen on |\n" +
+		"| `APP_SERVER_TIMEOUT` | time.Duration | `5s` |  | request timeout |\n"
+
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestWriteDotEnv(t *testing.T) {
+	var buf bytes.Buffer
+
+	spec := struct {
+		Name  string `json:"name" koanf:"name" required:"true" description:"name of the service"`
+		Greet string `json:"greet" koanf:"greet" default:"hello world"`
+	}{}
+
+	require.NoError(t, testConfig().Generate(&buf, envparse.FormatDotEnv, "APP", &spec))
+
+	expected := "# name of the service\n" +
+		"# type: string (required)\n" +
+		"APP_NAME=\n" +
+		"\n" +
+		"# type: string\n" +
+		"APP_GREET=\"hello world\"\n"
+
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestJSONSchema(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(t, testConfig().Generate(&buf, envparse.FormatJSONSchema, "APP", &docsSpec{}))
+
+	var schema map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &schema))
+
+	assert.Equal(t, "object", schema["type"])
+	assert.Equal(t, []any{"name"}, schema["required"])
+
+	props := schema["properties"].(map[string]any)
+
+	name := props["name"].(map[string]any)
+	assert.Equal(t, "string", name["type"])
+	assert.Equal(t, "APP_NAME", name["x-env"])
+
+	assert.Equal(t, map[string]any{"type": "boolean", "default": false, "x-env": "APP_DEBUG"}, props["debug"])
+	assert.Equal(t, float64(80), props["port"].(map[string]any)["default"])
+
+	hosts := props["hosts"].(map[string]any)
+	assert.Equal(t, "array", hosts["type"])
+	assert.Equal(t, []any{"a", "b"}, hosts["default"])
+
+	server := props["server"].(map[string]any)
+	assert.Equal(t, "object", server["type"])
+
+	timeout := server["properties"].(map[string]any)["timeout"].(map[string]any)
+	assert.Equal(t, "string", timeout["type"])
+	assert.Equal(t, "5s", timeout["default"])
+	assert.Equal(t, "request timeout", timeout["description"])
+}
+
+func TestGenerateUnknownFormat(t *testing.T) {
+	err := testConfig().Generate(&bytes.Buffer{}, "yaml", "APP", &docsSpec{})
+	assert.ErrorIs(t, err, envparse.ErrUnknownFormat)
+}
+
+func TestGenerateMain(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "config.env")
+
+	require.NoError(t, testConfig().GenerateMain("APP", &docsSpec{}, []string{"-format", envparse.FormatDotEnv, "-out", out}))
+
+	content, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "APP_PORT=80")
+
+	err = testConfig().GenerateMain("APP", &docsSpec{}, []string{"-format", "yaml", "-out", out})
+	require.ErrorIs(t, err, envparse.ErrUnknownFormat)
+
+	err = testConfig().GenerateMain("APP", &docsSpec{}, []string{"-unknown"})
+	require.Error(t, err)
+}
@@ -0,0 +1,33 @@
+package envparse
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUnsupportedType indicates that a field has a type that cannot be decoded from a string
+	ErrUnsupportedType = errors.New("unsupported type")
+	// ErrInvalidMapItem indicates that a map value contains an item that is not a key=value pair
+	ErrInvalidMapItem = errors.New("invalid map item")
+)
+
+// ParseError occurs when an environment variable cannot be converted to the type of its struct field
+type ParseError struct {
+	// Key is the environment variable name
+	Key string
+	// FullPath is the path of the field within the configuration
+	FullPath string
+	// Err is the underlying decoding error
+	Err error
+}
+
+// Error returns the ParseError in string format
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("envparse: could not parse %s (%s): %s", e.Key, e.FullPath, e.Err)
+}
+
+// Unwrap returns the underlying decoding error
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
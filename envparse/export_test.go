@@ -0,0 +1,9 @@
+package envparse
+
+// WithReadyHook sets a function called by Run once it watches the files and signals, so tests can
+// change files and send signals without sleeping
+func WithReadyHook(fn func()) WatchOption {
+	return func(o *watchOptions) {
+		o.ready = fn
+	}
+}
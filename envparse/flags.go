@@ -0,0 +1,146 @@
+package envparse
+
+import (
+	"flag"
+	"reflect"
+)
+
+// Flag is a command line flag generated for a configuration variable
+type Flag struct {
+	// Name is the flag name, see FlagName
+	Name string
+	// Usage is the help text built from the description tag and the environment variable name
+	Usage string
+	// Value holds the value passed on the command line
+	Value *FlagValue
+	// Info is the variable the flag sets
+	Info VarInfo
+}
+
+// Flags is a list of generated command line flags
+type Flags []Flag
+
+// FlagValue holds the command line value of a configuration variable. It implements flag.Value and,
+// through Type, pflag.Value; values are checked by decoding them into the field type when they are set
+// but the struct is only updated by Loader.Load so that flags keep their precedence over the environment
+type FlagValue struct {
+	typ   reflect.Type
+	value string
+	set   bool
+}
+
+// String returns the value passed on the command line, or the default value if the flag was not set
+func (v *FlagValue) String() string {
+	if v == nil {
+		return ""
+	}
+
+	return v.value
+}
+
+// Set checks that s can be decoded into the field type and stores it
+func (v *FlagValue) Set(s string) error {
+	if err := decodeValue(reflect.New(v.typ).Elem(), s); err != nil {
+		return err
+	}
+
+	v.value, v.set = s, true
+
+	return nil
+}
+
+// Type returns the name of the field type, used by pflag in help output
+func (v *FlagValue) Type() string {
+	t := v.typ
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t.String()
+}
+
+// IsBoolFlag allows boolean flags to be passed without a value, e.g. -debug
+func (v *FlagValue) IsBoolFlag() bool {
+	t := v.typ
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Bool
+}
+
+// IsSet reports whether the flag was set on the command line
+func (v *FlagValue) IsSet() bool {
+	return v.set
+}
+
+// Flags returns a command line flag for every variable gathered from spec. Flag names are the FullPath
+// below the root of prefix, help text comes from the description or doc tag, and the default value comes
+// from the default tag; defaults of sensitive fields are left out of the help text
+func (c Config) Flags(prefix string, spec interface{}) (Flags, error) {
+	infos, err := c.GatherEnvInfo(prefix, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := make(Flags, 0, len(infos))
+
+	for _, info := range infos {
+		value := &FlagValue{typ: info.Type}
+		if !info.Sensitive() {
+			value.value = info.Default()
+		}
+
+		usage := info.Description()
+		if usage != "" {
+			usage += " "
+		}
+
+		usage += "(env " + info.Key + ")"
+
+		flags = append(flags, Flag{Name: FlagName(prefix, info), Usage: usage, Value: value, Info: info})
+	}
+
+	return flags, nil
+}
+
+// RegisterFlags generates the flags of spec and registers them on fs; pass fs as Loader.Flags once it
+// is parsed to apply the flags that were set
+func (c Config) RegisterFlags(fs *flag.FlagSet, prefix string, spec interface{}) (Flags, error) {
+	flags, err := c.Flags(prefix, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	flags.Register(fs)
+
+	return flags, nil
+}
+
+// Register adds the flags to fs. To use pflag, register each flag with its Var method instead and pass
+// the flags as Loader.FlagValues:
+//
+//	for _, f := range flags {
+//		pf := pflags.VarPF(f.Value, f.Name, "", f.Usage)
+//		if f.Value.IsBoolFlag() {
+//			pf.NoOptDefVal = "true"
+//		}
+//	}
+func (f Flags) Register(fs *flag.FlagSet) {
+	for _, fl := range f {
+		fs.Var(fl.Value, fl.Name, fl.Usage)
+	}
+}
+
+// values returns the values of the flags that were set, keyed by flag name
+func (f Flags) values() map[string]string {
+	set := map[string]string{}
+
+	for _, fl := range f {
+		if fl.Value.IsSet() {
+			set[fl.Name] = fl.Value.String()
+		}
+	}
+
+	return set
+}
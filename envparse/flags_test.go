@@ -0,0 +1,107 @@
+package envparse_test
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theopenlane/utils/envparse"
+)
+
+type flagServer struct {
+	Listen  string        `json:"listen" koanf:"listen" default:":8080" description:"address to listen on"`
+	Timeout time.Duration `json:"timeout" koanf:"timeout" default:"5s"`
+}
+
+type flagSpec struct {
+	Debug    bool       `json:"debug" koanf:"debug"`
+	Level    string     `json:"level" koanf:"level" default:"info"`
+	Password string     `json:"password" koanf:"password" default:"changeme" sensitive:"true"`
+	Server   flagServer `json:"server" koanf:"server"`
+}
+
+func TestRegisterFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+
+	var out bytes.Buffer
+
+	fs.SetOutput(&out)
+
+	flags, err := testConfig().RegisterFlags(fs, "APP", &flagSpec{})
+	require.NoError(t, err)
+	require.Len(t, flags, 5)
+
+	listen := fs.Lookup("server.listen")
+	require.NotNil(t, listen)
+	assert.Equal(t, ":8080", listen.DefValue)
+	assert.Equal(t, "address to listen on (env APP_SERVER_LISTEN)", listen.Usage)
+	assert.Equal(t, "", fs.Lookup("password").DefValue)
+	assert.Equal(t, "time.Duration", flags[4].Value.Type())
+
+	fs.PrintDefaults()
+	assert.NotContains(t, out.String(), "changeme")
+
+	err = fs.Parse([]string{"-server.timeout=soon"})
+	assert.Error(t, err)
+
+	require.NoError(t, fs.Parse([]string{"-debug", "-server.listen=:7000", "-password", "secret"}))
+
+	loader := envparse.Loader{
+		Config: testConfig(),
+		Prefix: "APP",
+		Lookup: envparse.MapLookup(map[string]string{"APP_SERVER_LISTEN": ":6000", "APP_LEVEL": "warn"}),
+		Flags:  fs,
+	}
+
+	spec := flagSpec{}
+
+	report, err := loader.Load(&spec)
+	require.NoError(t, err)
+
+	assert.True(t, spec.Debug)
+	assert.Equal(t, "warn", spec.Level)
+	assert.Equal(t, "secret", spec.Password)
+	assert.Equal(t, ":7000", spec.Server.Listen)
+	assert.Equal(t, 5*time.Second, spec.Server.Timeout)
+
+	p, _ := report.Get("app.server.listen")
+	assert.Equal(t, envparse.OriginFlag, p.Origin)
+
+	p, _ = report.Get("app.server.timeout")
+	assert.Equal(t, envparse.OriginDefault, p.Origin, "unset flags do not override lower layers")
+}
+
+func TestLoaderFlagValues(t *testing.T) {
+	flags, err := testConfig().Flags("APP", &flagSpec{})
+	require.NoError(t, err)
+
+	// simulate a flag library other than the standard library setting the values
+	for _, f := range flags {
+		if f.Name == "level" {
+			require.NoError(t, f.Value.Set("debug"))
+		}
+
+		assert.Equal(t, f.Name == "debug", f.Value.IsBoolFlag(), f.Name)
+	}
+
+	loader := envparse.Loader{
+		Config:     testConfig(),
+		Prefix:     "APP",
+		Lookup:     envparse.MapLookup(map[string]string{"APP_LEVEL": "warn"}),
+		FlagValues: flags,
+	}
+
+	spec := flagSpec{}
+
+	report, err := loader.Load(&spec)
+	require.NoError(t, err)
+
+	assert.Equal(t, "debug", spec.Level)
+
+	p, _ := report.Get("app.level")
+	assert.Equal(t, envparse.Provenance{FullPath: "app.level", Key: "APP_LEVEL", Origin: envparse.OriginFlag, Source: "-level", Value: "debug"}, p)
+}
@@ -0,0 +1,426 @@
+package envparse
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Origin identifies the layer a configuration value came from
+type Origin string
+
+// Origins in increasing order of precedence
+const (
+	// OriginUnset is used for fields that no layer set and that had no initial value
+	OriginUnset Origin = "unset"
+	// OriginInitial is used for fields that kept the value they had before loading
+	OriginInitial Origin = "initial"
+	// OriginDefault is used for values from a default tag
+	OriginDefault Origin = "default"
+	// OriginFile is used for values from a configuration file
+	OriginFile Origin = "file"
+	// OriginEnv is used for values from an environment variable
+	OriginEnv Origin = "env"
+	// OriginFlag is used for values from a command line flag
+	OriginFlag Origin = "flag"
+)
+
+// ErrUnsupportedFileFormat indicates that a configuration file does not have a .yaml, .yml, .json or .toml extension
+var ErrUnsupportedFileFormat = errors.New("unsupported config file format")
+
+// Provenance records where the final value of a configuration variable came from
+type Provenance struct {
+	// FullPath is the path of the field within the configuration
+	FullPath string `json:"path"`
+	// Key is the environment variable name
+	Key string `json:"key"`
+	// Origin is the layer that set the value
+	Origin Origin `json:"origin"`
+	// Source names the file, environment variable or flag the value was read from
+	Source string `json:"source,omitempty"`
+	// Value is the final value, redacted for sensitive fields
+	Value string `json:"value"`
+}
+
+// Report lists the provenance of every configuration variable in the order they were gathered
+type Report []Provenance
+
+// Get returns the provenance of the variable with the given FullPath
+func (r Report) Get(fullPath string) (Provenance, bool) {
+	for _, p := range r {
+		if p.FullPath == fullPath {
+			return p, true
+		}
+	}
+
+	return Provenance{}, false
+}
+
+// Loader merges configuration from defaults, files, the environment and command line flags, in that
+// order of precedence, and records where every value came from. Files are keyed by the FieldTagName
+// (koanf) names below the root of the prefix, and flags are matched by FlagName
+//
+//	loader := envparse.Loader{
+//		Config: envparse.Config{FieldTagName: "koanf", Skipper: "-"},
+//		Prefix: "CORE",
+//		Files:  []string{"config.yaml"},
+//		Flags:  flagSet,
+//	}
+//
+//	report, err := loader.Load(&cfg)
+type Loader struct {
+	// Config controls how the struct is walked
+	Config Config
+	// Prefix is the environment variable prefix
+	Prefix string
+	// Files are read in order, later files override earlier ones; the format is chosen by extension
+	Files []string
+	// Lookup reads environment variables, defaults to os.LookupEnv
+	Lookup LookupFunc
+	// Environ lists the environment variables used to discover slice elements and map entries of
+	// structs, see Discover; defaults to os.Environ when Lookup is nil
+	Environ []string
+	// Flags holds parsed command line flags, only flags set on the command line are applied
+	Flags *flag.FlagSet
+	// FlagValues holds flags generated by Config.Flags and registered with another flag library such as
+	// pflag, only flags set on the command line are applied
+	FlagValues Flags
+	// SkipValidation disables validating the merged configuration
+	SkipValidation bool
+}
+
+// FlagName returns the command line flag name of a variable: its FullPath below the root of prefix,
+// which are the FieldTagName names joined by dots, e.g. cache.address
+func FlagName(prefix string, info VarInfo) string {
+	if base := pathRoot(prefix); base != "" {
+		return strings.TrimPrefix(info.FullPath, base+".")
+	}
+
+	return info.FullPath
+}
+
+// Load merges every layer into spec and returns the provenance of each variable; decoding errors of all
+// layers are returned together, followed by validation errors once the layers are merged
+func (l Loader) Load(spec interface{}) (Report, error) {
+	var errs []error
+
+	files := make([]map[string]any, len(l.Files))
+	names := l.Environ
+
+	var paths []string
+
+	if l.Lookup == nil && names == nil {
+		names = os.Environ()
+	}
+
+	for i, path := range l.Files {
+		values, err := readConfigFile(path)
+		if err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+
+		files[i] = values
+		paths = appendValuePaths(paths, pathRoot(l.Prefix), values)
+	}
+
+	keys := make([]string, 0, len(names))
+
+	for _, name := range names {
+		key, _, _ := strings.Cut(name, "=")
+		keys = append(keys, strings.ToUpper(key))
+	}
+
+	if err := l.Config.discover(l.Prefix, spec, gatherOptions{names: keys, paths: paths}); err != nil {
+		return nil, err
+	}
+
+	infos, err := l.Config.GatherEnvInfo(l.Prefix, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	report := make(Report, len(infos))
+
+	for i, info := range infos {
+		report[i] = Provenance{FullPath: info.FullPath, Key: info.Key, Origin: OriginInitial}
+
+		if isEmpty(info.Field) {
+			report[i].Origin = OriginUnset
+		}
+	}
+
+	for i, info := range infos {
+		if isEmpty(info.Field) && info.Default() != "" {
+			if err := applyDefault(info); err != nil {
+				errs = append(errs, err)
+			} else if !isEmpty(info.Field) {
+				report[i].Origin = OriginDefault
+			}
+		}
+	}
+
+	for n, path := range l.Files {
+		values := files[n]
+		if values == nil {
+			continue
+		}
+
+		for i, info := range infos {
+			v, ok := lookupPath(values, strings.Split(FlagName(l.Prefix, info), "."))
+			if !ok {
+				continue
+			}
+
+			if err := assignValue(info.Field, v); err != nil {
+				errs = append(errs, &ParseError{Key: info.Key, FullPath: info.FullPath, Err: fmt.Errorf("%s: %w", path, err)})
+
+				continue
+			}
+
+			report[i].Origin, report[i].Source = OriginFile, path
+		}
+	}
+
+	lookup := l.Lookup
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+
+	for i, info := range infos {
+		value, ok, err := lookupValue(info.Key, lookup)
+		if err == nil && ok {
+			err = decodeValue(info.Field, value)
+		}
+
+		if err != nil {
+			errs = append(errs, &ParseError{Key: info.Key, FullPath: info.FullPath, Err: err})
+
+			continue
+		}
+
+		if ok {
+			report[i].Origin, report[i].Source = OriginEnv, info.Key
+		}
+	}
+
+	set := l.FlagValues.values()
+
+	if l.Flags != nil {
+		l.Flags.Visit(func(f *flag.Flag) { set[f.Name] = f.Value.String() })
+	}
+
+	for i, info := range infos {
+		name := FlagName(l.Prefix, info)
+
+		value, ok := set[name]
+		if !ok {
+			continue
+		}
+
+		if err := decodeValue(info.Field, value); err != nil {
+			errs = append(errs, &ParseError{Key: info.Key, FullPath: info.FullPath, Err: fmt.Errorf("flag -%s: %w", name, err)})
+
+			continue
+		}
+
+		report[i].Origin, report[i].Source = OriginFlag, "-"+name
+	}
+
+	commitInfos(infos)
+
+	for i, info := range infos {
+		report[i].Value = info.DisplayValue()
+	}
+
+	if len(errs) > 0 {
+		return report, errors.Join(errs...)
+	}
+
+	if l.SkipValidation {
+		return report, nil
+	}
+
+	return report, validateInfos(infos)
+}
+
+// readConfigFile parses a YAML, JSON or TOML file into a map
+func readConfigFile(path string) (map[string]any, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]any{}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &values)
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		err = dec.Decode(&values)
+	case ".toml":
+		err = toml.Unmarshal(b, &values)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFileFormat, path)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return values, nil
+}
+
+// lookupPath returns the value found by following the path segments through nested maps and lists;
+// map keys that do not match exactly are matched ignoring case
+func lookupPath(values map[string]any, path []string) (any, bool) {
+	var cur any = values
+
+	for _, seg := range path {
+		switch v := cur.(type) {
+		case map[string]any:
+			next, ok := v[seg]
+			if !ok {
+				for k, item := range v {
+					if strings.EqualFold(k, seg) {
+						next, ok = item, true
+
+						break
+					}
+				}
+			}
+
+			if !ok {
+				return nil, false
+			}
+
+			cur = next
+		case []any:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+
+			cur = v[i]
+		default:
+			return nil, false
+		}
+	}
+
+	return cur, true
+}
+
+// appendValuePaths appends the FullPath of every value in a configuration file, e.g.
+// prefix.providers.0.name for providers[0].name, so that Discover creates the elements they set
+func appendValuePaths(paths []string, path string, v any) []string {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, item := range v {
+			paths = appendValuePaths(paths, joinPath(path, k), item)
+		}
+	case []any:
+		for i, item := range v {
+			paths = appendValuePaths(paths, joinPath(path, strconv.Itoa(i)), item)
+		}
+	default:
+		paths = append(paths, path)
+	}
+
+	return paths
+}
+
+// assignValue stores a value parsed from a configuration file in field; lists and maps are assigned
+// element by element and scalars are decoded from their string form
+func assignValue(field reflect.Value, v any) error {
+	if v == nil {
+		field.Set(reflect.Zero(field.Type()))
+
+		return nil
+	}
+
+	typ := field.Type()
+	if typ.Kind() == reflect.Ptr {
+		ptr := reflect.New(typ.Elem())
+		if err := assignValue(ptr.Elem(), v); err != nil {
+			return err
+		}
+
+		field.Set(ptr)
+
+		return nil
+	}
+
+	switch val := v.(type) {
+	case []any:
+		if typ.Kind() != reflect.Slice {
+			return fmt.Errorf("%w: cannot assign a list to %s", ErrUnsupportedType, typ)
+		}
+
+		slice := reflect.MakeSlice(typ, len(val), len(val))
+		for i, item := range val {
+			if err := assignValue(slice.Index(i), item); err != nil {
+				return err
+			}
+		}
+
+		field.Set(slice)
+
+		return nil
+	case map[string]any:
+		if typ.Kind() != reflect.Map {
+			return fmt.Errorf("%w: cannot assign a map to %s", ErrUnsupportedType, typ)
+		}
+
+		m := reflect.MakeMapWithSize(typ, len(val))
+
+		for k, item := range val {
+			key := reflect.New(typ.Key()).Elem()
+			if err := decodeValue(key, k); err != nil {
+				return err
+			}
+
+			elem := reflect.New(typ.Elem()).Elem()
+			if err := assignValue(elem, item); err != nil {
+				return err
+			}
+
+			m.SetMapIndex(key, elem)
+		}
+
+		field.Set(m)
+
+		return nil
+	case string:
+		resolved, err := resolveValue(val)
+		if err != nil {
+			return err
+		}
+
+		return decodeValue(field, resolved)
+	case encoding.TextMarshaler:
+		// YAML timestamps and TOML dates are parsed into time.Time and toml.Local* values, which
+		// fmt.Sprint renders in a form their UnmarshalText does not accept
+		text, err := val.MarshalText()
+		if err != nil {
+			return err
+		}
+
+		return decodeValue(field, string(text))
+	default:
+		return decodeValue(field, fmt.Sprint(val))
+	}
+}
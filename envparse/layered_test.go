@@ -0,0 +1,245 @@
+package envparse_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theopenlane/utils/envparse"
+)
+
+type layeredServer struct {
+	Listen  string        `json:"listen" koanf:"listen" default:":8080"`
+	Timeout time.Duration `json:"timeout" koanf:"timeout" default:"5s"`
+}
+
+type layeredSpec struct {
+	Name      string            `json:"name" koanf:"name" default:"app"`
+	Level     string            `json:"level" koanf:"level" default:"info" oneof:"debug,info,warn"`
+	Hosts     []string          `json:"hosts" koanf:"hosts"`
+	Labels    map[string]string `json:"labels" koanf:"labels"`
+	Workers   int               `json:"workers" koanf:"workers"`
+	Password  string            `json:"password" koanf:"password" sensitive:"true"`
+	Untouched string            `json:"untouched" koanf:"untouched"`
+	Server    layeredServer     `json:"server" koanf:"server"`
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func TestLoaderPrecedence(t *testing.T) {
+	yamlFile := writeFile(t, "config.yaml", `
+name: from-yaml
+level: warn
+hosts: [a, b]
+labels:
+  team: core
+workers: 4
+password: yaml-secret
+server:
+  listen: ":9000"
+`)
+
+	jsonFile := writeFile(t, "override.json", `{"workers": 1000000, "server": {"timeout": "10s"}}`)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("server.listen", "", "")
+	fs.String("name", "", "")
+	require.NoError(t, fs.Parse([]string{"-server.listen=:7000"}))
+
+	loader := envparse.Loader{
+		Config: testConfig(),
+		Prefix: "APP",
+		Files:  []string{yamlFile, jsonFile},
+		Lookup: envparse.MapLookup(map[string]string{"APP_LEVEL": "debug"}),
+		Flags:  fs,
+	}
+
+	spec := layeredSpec{}
+
+	report, err := loader.Load(&spec)
+	require.NoError(t, err)
+
+	assert.Equal(t, "from-yaml", spec.Name)
+	assert.Equal(t, "debug", spec.Level)
+	assert.Equal(t, []string{"a", "b"}, spec.Hosts)
+	assert.Equal(t, map[string]string{"team": "core"}, spec.Labels)
+	assert.Equal(t, 1000000, spec.Workers)
+	assert.Equal(t, ":7000", spec.Server.Listen)
+	assert.Equal(t, 10*time.Second, spec.Server.Timeout)
+
+	expected := map[string]envparse.Provenance{
+		"app.name":           {FullPath: "app.name", Key: "APP_NAME", Origin: envparse.OriginFile, Source: yamlFile, Value: "from-yaml"},
+		"app.level":          {FullPath: "app.level", Key: "APP_LEVEL", Origin: envparse.OriginEnv, Source: "APP_LEVEL", Value: "debug"},
+		"app.workers":        {FullPath: "app.workers", Key: "APP_WORKERS", Origin: envparse.OriginFile, Source: jsonFile, Value: "1000000"},
+		"app.password":       {FullPath: "app.password", Key: "APP_PASSWORD", Origin: envparse.OriginFile, Source: yamlFile, Value: envparse.RedactedValue},
+		"app.untouched":      {FullPath: "app.untouched", Key: "APP_UNTOUCHED", Origin: envparse.OriginUnset},
+		"app.server.listen":  {FullPath: "app.server.listen", Key: "APP_SERVER_LISTEN", Origin: envparse.OriginFlag, Source: "-server.listen", Value: ":7000"},
+		"app.server.timeout": {FullPath: "app.server.timeout", Key: "APP_SERVER_TIMEOUT", Origin: envparse.OriginFile, Source: jsonFile, Value: "10s"},
+	}
+
+	for path, want := range expected {
+		got, ok := report.Get(path)
+		require.True(t, ok, path)
+		assert.Equal(t, want, got, path)
+	}
+}
+
+func TestLoaderDefaultsAndTOML(t *testing.T) {
+	tomlFile := writeFile(t, "config.toml", `
+workers = 2
+
+[server]
+timeout = "1m"
+`)
+
+	loader := envparse.Loader{
+		Config: testConfig(),
+		Prefix: "APP",
+		Files:  []string{tomlFile},
+		Lookup: envparse.MapLookup(nil),
+	}
+
+	spec := layeredSpec{Untouched: "initial"}
+
+	report, err := loader.Load(&spec)
+	require.NoError(t, err)
+
+	assert.Equal(t, "app", spec.Name)
+	assert.Equal(t, 2, spec.Workers)
+	assert.Equal(t, time.Minute, spec.Server.Timeout)
+
+	p, _ := report.Get("app.name")
+	assert.Equal(t, envparse.OriginDefault, p.Origin)
+
+	p, _ = report.Get("app.untouched")
+	assert.Equal(t, envparse.OriginInitial, p.Origin)
+}
+
+type layeredDatabase struct {
+	DialTimeout time.Duration `json:"dial_timeout" koanf:"dialtimeout"`
+	Host        string        `json:"host" koanf:"host"`
+	Port        int           `json:"port" koanf:"port"`
+}
+
+type layeredTagSpec struct {
+	DB layeredDatabase `json:"database" koanf:"db"`
+}
+
+func TestLoaderKoanfPaths(t *testing.T) {
+	yamlFile := writeFile(t, "config.yaml", `
+db:
+  dialtimeout: 3s
+  host: db.internal
+`)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Int("db.port", 0, "")
+	require.NoError(t, fs.Parse([]string{"-db.port=5433"}))
+
+	loader := envparse.Loader{
+		Config: testConfig(),
+		Prefix: "APP",
+		Files:  []string{yamlFile},
+		Lookup: envparse.MapLookup(map[string]string{"APP_DATABASE_HOST": "db.env"}),
+		Flags:  fs,
+	}
+
+	spec := layeredTagSpec{}
+
+	report, err := loader.Load(&spec)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3*time.Second, spec.DB.DialTimeout, "files are keyed by koanf tags")
+	assert.Equal(t, "db.env", spec.DB.Host, "env names are built from json tags")
+	assert.Equal(t, 5433, spec.DB.Port, "flags are named by koanf tags")
+
+	expected := map[string]envparse.Provenance{
+		"app.db.dialtimeout": {FullPath: "app.db.dialtimeout", Key: "APP_DATABASE_DIALTIMEOUT", Origin: envparse.OriginFile, Source: yamlFile, Value: "3s"},
+		"app.db.host":        {FullPath: "app.db.host", Key: "APP_DATABASE_HOST", Origin: envparse.OriginEnv, Source: "APP_DATABASE_HOST", Value: "db.env"},
+		"app.db.port":        {FullPath: "app.db.port", Key: "APP_DATABASE_PORT", Origin: envparse.OriginFlag, Source: "-db.port", Value: "5433"},
+	}
+
+	for path, want := range expected {
+		got, ok := report.Get(path)
+		require.True(t, ok, path)
+		assert.Equal(t, want, got, path)
+	}
+}
+
+func TestLoaderTimeValues(t *testing.T) {
+	type timeSpec struct {
+		At    time.Time  `json:"at" koanf:"at"`
+		Until *time.Time `json:"until" koanf:"until"`
+	}
+
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	until := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+
+	files := map[string]string{
+		"config.yaml": "at: 2024-01-02T03:04:05Z\nuntil: 2024-06-30\n",
+		"config.toml": "at = 2024-01-02T03:04:05Z\nuntil = 2024-06-30T00:00:00Z\n",
+		"config.json": `{"at": "2024-01-02T03:04:05Z", "until": "2024-06-30T00:00:00Z"}`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			loader := envparse.Loader{
+				Config: testConfig(),
+				Prefix: "APP",
+				Files:  []string{writeFile(t, name, content)},
+				Lookup: envparse.MapLookup(nil),
+			}
+
+			spec := timeSpec{}
+
+			_, err := loader.Load(&spec)
+			require.NoError(t, err)
+
+			assert.True(t, at.Equal(spec.At), spec.At)
+			require.NotNil(t, spec.Until)
+			assert.True(t, until.Equal(*spec.Until), *spec.Until)
+		})
+	}
+}
+
+func TestLoaderErrors(t *testing.T) {
+	loader := envparse.Loader{
+		Config: testConfig(),
+		Prefix: "APP",
+		Files:  []string{writeFile(t, "config.ini", "")},
+		Lookup: envparse.MapLookup(nil),
+	}
+
+	_, err := loader.Load(&layeredSpec{})
+	assert.ErrorIs(t, err, envparse.ErrUnsupportedFileFormat)
+
+	loader.Files = []string{writeFile(t, "config.yaml", "workers: many\nlevel: trace\n")}
+
+	_, err = loader.Load(&layeredSpec{})
+
+	var perr *envparse.ParseError
+	require.ErrorAs(t, err, &perr)
+	assert.Equal(t, "APP_WORKERS", perr.Key)
+
+	loader.Files = []string{writeFile(t, "config.yaml", "level: trace\n")}
+
+	_, err = loader.Load(&layeredSpec{})
+	assert.ErrorIs(t, err, envparse.ErrNotOneOf)
+
+	loader.SkipValidation = true
+
+	_, err = loader.Load(&layeredSpec{})
+	assert.NoError(t, err)
+}
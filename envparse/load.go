@@ -0,0 +1,66 @@
+package envparse
+
+import (
+	"errors"
+	"os"
+)
+
+// LookupFunc returns the value of the named variable and whether it is set, matching os.LookupEnv
+type LookupFunc func(key string) (string, bool)
+
+// MapLookup returns a LookupFunc that reads variables from m instead of the process environment
+func MapLookup(m map[string]string) LookupFunc {
+	return func(key string) (string, bool) {
+		v, ok := m[key]
+
+		return v, ok
+	}
+}
+
+// Load reads the environment variable of every field gathered by GatherEnvInfo and decodes it into spec;
+// fields without a set variable are left unchanged and every variable that fails to decode is reported
+// as a *ParseError in the returned joined error
+//
+// When a variable is not set but KEY_FILE is, the value is read from the named file, and values of the
+// form keyring://service/account are read from the system keyring
+//
+// Slice elements and map entries of structs named by the environment are created first, see Discover
+func (c Config) Load(prefix string, spec interface{}) error {
+	if err := c.Discover(prefix, spec, os.Environ()); err != nil {
+		return err
+	}
+
+	return c.LoadFrom(prefix, spec, os.LookupEnv)
+}
+
+// LoadFrom is like Load but reads variables using lookup instead of the process environment; as lookup
+// cannot list variables, call Discover first to load slices and maps of structs
+func (c Config) LoadFrom(prefix string, spec interface{}, lookup LookupFunc) error {
+	infos, err := c.GatherEnvInfo(prefix, spec)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, info := range infos {
+		value, ok, err := lookupValue(info.Key, lookup)
+		if err != nil {
+			errs = append(errs, &ParseError{Key: info.Key, FullPath: info.FullPath, Err: err})
+
+			continue
+		}
+
+		if !ok {
+			continue
+		}
+
+		if err := decodeValue(info.Field, value); err != nil {
+			errs = append(errs, &ParseError{Key: info.Key, FullPath: info.FullPath, Err: err})
+		}
+	}
+
+	commitInfos(infos)
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,158 @@
+package envparse_test
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theopenlane/utils/cache"
+	"github.com/theopenlane/utils/envparse"
+)
+
+type loadNested struct {
+	Name  string `json:"name" koanf:"name"`
+	Count *int   `json:"count" koanf:"count"`
+}
+
+type loadSpec struct {
+	Str      string            `json:"str" koanf:"str"`
+	Int      int               `json:"int" koanf:"int"`
+	Int8     int8              `json:"int8" koanf:"int8"`
+	Uint     uint16            `json:"uint" koanf:"uint"`
+	Float    float64           `json:"float" koanf:"float"`
+	Bool     bool              `json:"bool" koanf:"bool"`
+	Duration time.Duration     `json:"duration" koanf:"duration"`
+	Strings  []string          `json:"strings" koanf:"strings"`
+	Ints     []int             `json:"ints" koanf:"ints"`
+	Bytes    []byte            `json:"bytes" koanf:"bytes"`
+	Labels   map[string]string `json:"labels" koanf:"labels"`
+	Limits   map[string]int    `json:"limits" koanf:"limits"`
+	Ptr      *string           `json:"ptr" koanf:"ptr"`
+	IP       net.IP            `json:"ip" koanf:"ip"`
+	Time     time.Time         `json:"time" koanf:"time"`
+	TimePtr  *time.Time        `json:"timeptr" koanf:"timeptr"`
+	Prefix   netip.Prefix      `json:"prefix" koanf:"prefix"`
+	Nested   loadNested        `json:"nested" koanf:"nested"`
+	NestPtr  *loadNested       `json:"nestptr" koanf:"nestptr"`
+	Skipped  string            `json:"skipped" koanf:"-"`
+	Unset    string            `json:"unset" koanf:"unset"`
+}
+
+func testConfig() envparse.Config {
+	return envparse.Config{FieldTagName: "koanf", Skipper: "-"}
+}
+
+func TestLoad(t *testing.T) {
+	env := map[string]string{
+		"APP_STR":           "hello",
+		"APP_INT":           "-42",
+		"APP_INT8":          "0x10",
+		"APP_UINT":          "65535",
+		"APP_FLOAT":         "3.5",
+		"APP_BOOL":          "true",
+		"APP_DURATION":      "1m30s",
+		"APP_STRINGS":       "a, b ,c",
+		"APP_INTS":          "1,2,3",
+		"APP_BYTES":         "raw,bytes",
+		"APP_LABELS":        "env=prod, team = core",
+		"APP_LIMITS":        "read=10,write=5",
+		"APP_PTR":           "pointer",
+		"APP_IP":            "10.0.0.1",
+		"APP_TIME":          "2024-05-01T12:30:00Z",
+		"APP_TIMEPTR":       "2024-06-01T00:00:00Z",
+		"APP_PREFIX":        "10.0.0.0/8",
+		"APP_NESTED_NAME":   "inner",
+		"APP_NESTED_COUNT":  "7",
+		"APP_NESTPTR_NAME":  "via-pointer",
+		"APP_SKIPPED":       "ignored",
+		"APP_UNKNOWN_FIELD": "ignored",
+	}
+
+	spec := loadSpec{Unset: "keep"}
+
+	require.NoError(t, testConfig().LoadFrom("APP", &spec, envparse.MapLookup(env)))
+
+	assert.Equal(t, "hello", spec.Str)
+	assert.Equal(t, -42, spec.Int)
+	assert.Equal(t, int8(16), spec.Int8)
+	assert.Equal(t, uint16(65535), spec.Uint)
+	assert.InDelta(t, 3.5, spec.Float, 0)
+	assert.True(t, spec.Bool)
+	assert.Equal(t, 90*time.Second, spec.Duration)
+	assert.Equal(t, []string{"a", "b", "c"}, spec.Strings)
+	assert.Equal(t, []int{1, 2, 3}, spec.Ints)
+	assert.Equal(t, []byte("raw,bytes"), spec.Bytes)
+	assert.Equal(t, map[string]string{"env": "prod", "team": "core"}, spec.Labels)
+	assert.Equal(t, map[string]int{"read": 10, "write": 5}, spec.Limits)
+	require.NotNil(t, spec.Ptr)
+	assert.Equal(t, "pointer", *spec.Ptr)
+	assert.Equal(t, "10.0.0.1", spec.IP.String())
+	assert.Equal(t, time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC), spec.Time)
+	require.NotNil(t, spec.TimePtr)
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), *spec.TimePtr)
+	assert.Equal(t, netip.MustParsePrefix("10.0.0.0/8"), spec.Prefix)
+	assert.Equal(t, "inner", spec.Nested.Name)
+	require.NotNil(t, spec.Nested.Count)
+	assert.Equal(t, 7, *spec.Nested.Count)
+	require.NotNil(t, spec.NestPtr)
+	assert.Equal(t, "via-pointer", spec.NestPtr.Name)
+	assert.Empty(t, spec.Skipped)
+	assert.Equal(t, "keep", spec.Unset)
+}
+
+func TestLoadAggregatesErrors(t *testing.T) {
+	env := map[string]string{
+		"APP_INT":      "not-a-number",
+		"APP_DURATION": "forever",
+		"APP_LABELS":   "novalue",
+		"APP_PTR":      "fine",
+		"APP_STR":      "ok",
+	}
+
+	spec := loadSpec{}
+
+	err := testConfig().LoadFrom("APP", &spec, envparse.MapLookup(env))
+	require.Error(t, err)
+
+	var keys []string
+
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var perr *envparse.ParseError
+		require.True(t, errors.As(e, &perr))
+
+		keys = append(keys, perr.Key)
+	}
+
+	assert.ElementsMatch(t, []string{"APP_INT", "APP_DURATION", "APP_LABELS"}, keys)
+	assert.ErrorIs(t, err, envparse.ErrInvalidMapItem)
+	assert.Contains(t, err.Error(), "app.int")
+
+	// valid variables are still loaded
+	assert.Equal(t, "ok", spec.Str)
+}
+
+func TestLoadFromEnvironment(t *testing.T) {
+	t.Setenv("CORE_CACHE_ADDRESS", "redis:6379")
+	t.Setenv("CORE_CACHE_DIALTIMEOUT", "10s")
+	t.Setenv("CORE_CACHE_MAXRETRIES", "-1")
+
+	spec := struct {
+		Cache cache.Config `json:"cache" koanf:"cache"`
+	}{}
+
+	require.NoError(t, testConfig().Load("CORE", &spec))
+
+	assert.Equal(t, "redis:6379", spec.Cache.Address)
+	assert.Equal(t, 10*time.Second, spec.Cache.DialTimeout)
+	assert.Equal(t, -1, spec.Cache.MaxRetries)
+}
+
+func TestLoadInvalidSpecification(t *testing.T) {
+	err := testConfig().Load("APP", loadSpec{})
+	assert.ErrorIs(t, err, envparse.ErrInvalidSpecification)
+}
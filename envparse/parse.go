@@ -5,8 +5,6 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
-
-	"github.com/stoewer/go-strcase"
 )
 
 // ErrInvalidSpecification indicates that a specification is of the wrong type.
@@ -26,10 +24,47 @@ type VarInfo struct {
 	Key       string
 	Type      reflect.Type
 	Tags      reflect.StructTag
+	// Field is the settable struct field the variable is loaded into
+	Field reflect.Value
+
+	// commit stores Field back into the spec for fields of structs held by value in a map or created
+	// for a nil pointer
+	commit func()
 }
 
 // GatherEnvInfo gathers information about the specified struct, including defaults and environment variable names.
+//
+// Slices and maps of structs are walked element by element: the fields of the element at index 0 of a
+// providers slice are named PREFIX_PROVIDERS_0_NAME and those of the element with map key github are
+// named PREFIX_PROVIDERS_GITHUB_NAME. Use Discover to create the elements named by environment variables.
+//
+// GatherEnvInfo does not modify spec: the fields of nil struct pointers are gathered from new zero structs
+// that are not stored, so setting their Field has no effect on spec. It is safe to call on a spec that
+// is read concurrently.
 func (c Config) GatherEnvInfo(prefix string, spec interface{}) ([]VarInfo, error) {
+	return c.gatherEnvInfo(prefix, spec, gatherOptions{})
+}
+
+// gatherOptions changes how gatherEnvInfo walks slices and maps of structs
+type gatherOptions struct {
+	// placeholders describes every collection by a single zero element keyed by IndexPlaceholder or
+	// KeyPlaceholder instead of its current elements
+	placeholders bool
+	// names of variables used to grow collections before they are walked
+	names []string
+	// paths of configuration file values used to grow collections before they are walked, matched
+	// against FullPath as files are keyed by FieldTagName names
+	paths []string
+}
+
+func (c Config) gatherEnvInfo(prefix string, spec interface{}, opts gatherOptions) ([]VarInfo, error) {
+	return c.gatherPath(prefix, pathRoot(prefix), spec, opts)
+}
+
+// gatherPath gathers the fields of spec below the env prefix and the FullPath path. Env names of nested
+// structs are built from their json tags while paths are built from their FieldTagName names, which
+// file layers and flags are keyed by
+func (c Config) gatherPath(prefix, path string, spec interface{}, opts gatherOptions) ([]VarInfo, error) {
 	s := reflect.ValueOf(spec)
 
 	// Ensure the specification is a pointer to a struct
@@ -50,21 +85,24 @@ func (c Config) GatherEnvInfo(prefix string, spec interface{}) ([]VarInfo, error
 	// Iterate over the struct fields
 	for i := range s.NumField() {
 		f := s.Field(i)
+		field := f
 		ftype := typeOfSpec.Field(i)
 
 		if !f.CanSet() {
 			continue
 		}
 
+		var commit func()
+
 		for f.Kind() == reflect.Ptr {
 			if f.IsNil() {
-				if f.Type().Elem().Kind() != reflect.Struct {
-					// nil pointer to a non-struct: leave it alone
+				if !isStructElem(f.Type().Elem()) {
+					// nil pointer to a non-struct or a type decoded from text: leave it alone
 					break
 				}
 
-				// nil pointer to struct: create a zero instance
-				f.Set(reflect.New(f.Type().Elem()))
+				// nil pointer to struct: walk a zero instance, stored by commit once it is loaded
+				f, commit = newElem(f, nil)
 			}
 
 			f = f.Elem()
@@ -81,6 +119,7 @@ func (c Config) GatherEnvInfo(prefix string, spec interface{}) ([]VarInfo, error
 			FullPath:  ftype.Name,
 			Type:      ftype.Type,
 			Tags:      ftype.Tag,
+			Field:     field,
 		}
 
 		// Default to the field name as the env var name (will be upcased)
@@ -88,27 +127,47 @@ func (c Config) GatherEnvInfo(prefix string, spec interface{}) ([]VarInfo, error
 
 		if prefix != "" {
 			info.Key = fmt.Sprintf("%s_%s", prefix, info.Key)
-			info.FullPath = fmt.Sprintf("%s.%s", strcase.LowerCamelCase(strings.ReplaceAll(prefix, "_", ".")), info.FieldName) // nolint: gocritic
+		}
+
+		if path != "" {
+			info.FullPath = path + "." + info.FieldName
 		}
 
 		info.Key = strings.ToUpper(info.Key)
 		infos = append(infos, info)
 
-		if f.Kind() == reflect.Struct {
-			innerPrefix := prefix
+		if isStructCollection(f.Type()) {
+			elemInfos, err := c.gatherCollection(prefix+"_"+info.Tags.Get("json"), joinPath(path, info.FieldName), f, opts)
+			if err != nil {
+				return nil, err
+			}
+
+			infos = append(infos[:len(infos)-1], elemInfos...)
+
+			continue
+		}
+
+		// structs such as time.Time and netip.Prefix that decode themselves from text are loaded as values
+		if isStructElem(f.Type()) {
+			innerPrefix, innerPath := prefix, path
 
 			if !ftype.Anonymous {
 				innerPrefix = prefix + "_" + info.Tags.Get("json")
+				innerPath = joinPath(path, info.FieldName)
 			}
 
 			embeddedPtr := f.Addr().Interface()
 
 			// Recursively gather information about the embedded struct
-			embeddedInfos, err := c.GatherEnvInfo(innerPrefix, embeddedPtr)
+			embeddedInfos, err := c.gatherPath(innerPrefix, innerPath, embeddedPtr, opts)
 			if err != nil {
 				return nil, err
 			}
 
+			if commit != nil {
+				chainCommit(embeddedInfos, commit)
+			}
+
 			infos = append(infos[:len(infos)-1], embeddedInfos...)
 
 			continue
@@ -118,6 +177,15 @@ func (c Config) GatherEnvInfo(prefix string, spec interface{}) ([]VarInfo, error
 	return infos, nil
 }
 
+// joinPath appends the segment seg to the FullPath path
+func joinPath(path, seg string) string {
+	if path == "" {
+		return seg
+	}
+
+	return path + "." + seg
+}
+
 func (c Config) getFieldName(ftype reflect.StructField) string {
 	if ftype.Tag.Get(c.FieldTagName) != "" {
 		return ftype.Tag.Get(c.FieldTagName)
@@ -0,0 +1,146 @@
+package envparse
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/theopenlane/utils/keyring"
+)
+
+const (
+	// SensitiveTag marks a field holding a secret when set to true; its value is redacted whenever it is printed
+	SensitiveTag = "sensitive"
+	// RedactedValue replaces the value of sensitive fields
+	RedactedValue = "[REDACTED]"
+	// FileSuffix is appended to a variable name to read its value from a file, e.g. FOO_FILE=/run/secrets/foo
+	FileSuffix = "_FILE"
+	// KeyringScheme prefixes values that are read from the system keyring, e.g. keyring://service/account
+	KeyringScheme = "keyring://"
+)
+
+var (
+	// ErrAmbiguousValue indicates that both a variable and its _FILE variant are set
+	ErrAmbiguousValue = errors.New("both variable and file variable are set")
+	// ErrInvalidKeyringReference indicates that a keyring:// value is not in the form keyring://service/account
+	ErrInvalidKeyringReference = errors.New("invalid keyring reference, expected keyring://service/account")
+)
+
+// Sensitive reports whether the variable is marked as holding a secret
+func (v VarInfo) Sensitive() bool {
+	sensitive, _ := strconv.ParseBool(v.Tags.Get(SensitiveTag))
+
+	return sensitive
+}
+
+// Value returns the current value of the field in the same format accepted from the environment
+func (v VarInfo) Value() string {
+	if !v.Field.IsValid() {
+		return ""
+	}
+
+	return encodeValue(v.Field)
+}
+
+// DisplayValue returns the current value of the field, or RedactedValue if the field is sensitive and set
+func (v VarInfo) DisplayValue() string {
+	return redact(v, v.Value())
+}
+
+// String returns the variable as KEY=value, redacting sensitive values
+func (v VarInfo) String() string {
+	return v.Key + "=" + v.DisplayValue()
+}
+
+// GoString returns the variable for the %#v verb, redacting sensitive values
+func (v VarInfo) GoString() string {
+	return fmt.Sprintf("envparse.VarInfo{FieldName:%q, FullPath:%q, Key:%q, Type:%v, Value:%q}",
+		v.FieldName, v.FullPath, v.Key, v.Type, v.DisplayValue())
+}
+
+// LogValue returns the variable for structured logging, redacting sensitive values
+func (v VarInfo) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("key", v.Key),
+		slog.String("path", v.FullPath),
+		slog.String("value", v.DisplayValue()),
+	)
+}
+
+// redact returns RedactedValue in place of a non-empty value of a sensitive variable
+func redact(info VarInfo, value string) string {
+	if value != "" && info.Sensitive() {
+		return RedactedValue
+	}
+
+	return value
+}
+
+// Dump writes the current value of every variable of spec to w as KEY=value lines, redacting sensitive values
+func (c Config) Dump(w io.Writer, prefix string, spec interface{}) error {
+	infos, err := c.GatherEnvInfo(prefix, spec)
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+
+	for _, info := range infos {
+		sb.WriteString(info.String())
+		sb.WriteString("\n")
+	}
+
+	_, err = io.WriteString(w, sb.String())
+
+	return err
+}
+
+// lookupValue returns the value of the variable from lookup, reading it from the file named by the
+// _FILE variable when the variable itself is not set, and resolving keyring:// references
+func lookupValue(key string, lookup LookupFunc) (string, bool, error) {
+	value, ok := lookup(key)
+
+	path, fileOK := lookup(key + FileSuffix)
+	if fileOK && path != "" {
+		if ok {
+			return "", false, fmt.Errorf("%w: %s and %s", ErrAmbiguousValue, key, key+FileSuffix)
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", false, err
+		}
+
+		value, ok = strings.TrimRight(string(b), "\r\n"), true
+	}
+
+	if !ok {
+		return "", false, nil
+	}
+
+	value, err := resolveValue(value)
+	if err != nil {
+		return "", false, err
+	}
+
+	return value, true, nil
+}
+
+// resolveValue replaces a keyring://service/account reference with the secret stored in the keyring
+func resolveValue(value string) (string, error) {
+	ref, ok := strings.CutPrefix(value, KeyringScheme)
+	if !ok {
+		return value, nil
+	}
+
+	service, account, found := strings.Cut(ref, "/")
+	if !found || service == "" || account == "" {
+		return "", ErrInvalidKeyringReference
+	}
+
+	return keyring.QueryKeyring(service, account)
+}
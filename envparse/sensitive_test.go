@@ -0,0 +1,110 @@
+package envparse_test
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gokeyring "github.com/zalando/go-keyring"
+
+	"github.com/theopenlane/utils/cache"
+	"github.com/theopenlane/utils/envparse"
+	"github.com/theopenlane/utils/keyring"
+)
+
+type sensitiveSpec struct {
+	User     string `json:"user" koanf:"user"`
+	Password string `json:"password" koanf:"password" sensitive:"true" default:"changeme"`
+	APIKey   string `json:"apikey" koanf:"apikey" sensitive:"true"`
+}
+
+func TestSensitiveRedaction(t *testing.T) {
+	spec := sensitiveSpec{User: "admin", Password: "hunter2"}
+
+	infos, err := testConfig().GatherEnvInfo("APP", &spec)
+	require.NoError(t, err)
+
+	assert.Equal(t, "APP_USER=admin", fmt.Sprint(infos[0]))
+	assert.Equal(t, "APP_PASSWORD="+envparse.RedactedValue, fmt.Sprintf("%v", infos[1]))
+	assert.Equal(t, "APP_PASSWORD="+envparse.RedactedValue, fmt.Sprintf("%+v", infos[1]))
+	assert.NotContains(t, fmt.Sprintf("%#v", infos[1]), "hunter2")
+	assert.Equal(t, "APP_APIKEY=", infos[2].String(), "empty values are not redacted")
+	assert.Equal(t, "hunter2", infos[1].Value())
+
+	var buf bytes.Buffer
+
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("config", slog.Any("var", infos[1]))
+	assert.NotContains(t, buf.String(), "hunter2")
+
+	buf.Reset()
+
+	require.NoError(t, testConfig().Dump(&buf, "APP", &spec))
+	assert.Equal(t, "APP_USER=admin\nAPP_PASSWORD=[REDACTED]\nAPP_APIKEY=\n", buf.String())
+}
+
+func TestSensitiveDocs(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(t, testConfig().Generate(&buf, envparse.FormatDotEnv, "APP", &sensitiveSpec{}))
+	assert.NotContains(t, buf.String(), "changeme")
+	assert.Contains(t, buf.String(), "# type: string (sensitive)\nAPP_PASSWORD=\n")
+
+	buf.Reset()
+
+	require.NoError(t, testConfig().Generate(&buf, envparse.FormatMarkdown, "APP", &sensitiveSpec{}))
+	assert.NotContains(t, buf.String(), "changeme")
+
+	buf.Reset()
+
+	require.NoError(t, testConfig().Generate(&buf, envparse.FormatJSONSchema, "APP", &sensitiveSpec{}))
+	assert.NotContains(t, buf.String(), "changeme")
+}
+
+func TestCachePasswordIsSensitive(t *testing.T) {
+	spec := struct {
+		Cache cache.Config `json:"cache" koanf:"cache"`
+	}{Cache: cache.Config{Password: "redis-pass"}}
+
+	var buf bytes.Buffer
+
+	require.NoError(t, testConfig().Dump(&buf, "CORE", &spec))
+	assert.Contains(t, buf.String(), "CORE_CACHE_PASSWORD=[REDACTED]")
+	assert.NotContains(t, buf.String(), "redis-pass")
+}
+
+func TestLoadFileIndirection(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(path, []byte("from-file\n"), 0o600))
+
+	spec := sensitiveSpec{}
+
+	env := envparse.MapLookup(map[string]string{"APP_PASSWORD_FILE": path})
+	require.NoError(t, testConfig().LoadFrom("APP", &spec, env))
+	assert.Equal(t, "from-file", spec.Password)
+
+	env = envparse.MapLookup(map[string]string{"APP_PASSWORD_FILE": path, "APP_PASSWORD": "direct"})
+	assert.ErrorIs(t, testConfig().LoadFrom("APP", &spec, env), envparse.ErrAmbiguousValue)
+
+	env = envparse.MapLookup(map[string]string{"APP_PASSWORD_FILE": filepath.Join(t.TempDir(), "missing")})
+	assert.ErrorIs(t, testConfig().LoadFrom("APP", &spec, env), os.ErrNotExist)
+}
+
+func TestLoadKeyringIndirection(t *testing.T) {
+	gokeyring.MockInit()
+
+	require.NoError(t, keyring.SetKeying("openlane", "api", []byte("from-keyring")))
+
+	spec := sensitiveSpec{}
+
+	env := envparse.MapLookup(map[string]string{"APP_APIKEY": "keyring://openlane/api"})
+	require.NoError(t, testConfig().LoadFrom("APP", &spec, env))
+	assert.Equal(t, "from-keyring", spec.APIKey)
+
+	env = envparse.MapLookup(map[string]string{"APP_APIKEY": "keyring://openlane"})
+	assert.ErrorIs(t, testConfig().LoadFrom("APP", &spec, env), envparse.ErrInvalidKeyringReference)
+}
@@ -0,0 +1,343 @@
+package envparse
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Struct tags used by Validate, RequiredTag is shared with the documentation generators
+const (
+	// MinTag is the minimum value of a number or duration, or the minimum length of a string, slice or map
+	MinTag = "min"
+	// MaxTag is the maximum value of a number or duration, or the maximum length of a string, slice or map
+	MaxTag = "max"
+	// OneOfTag is a comma-separated list of allowed values
+	OneOfTag = "oneof"
+	// RegexpTag is a regular expression that string values must match
+	RegexpTag = "regexp"
+	// URLTag requires string values to be absolute URLs when set to true
+	URLTag = "url"
+)
+
+var (
+	// ErrRequired indicates that a required field is not set
+	ErrRequired = errors.New("value is required")
+	// ErrOutOfRange indicates that a value is outside of its min or max bounds
+	ErrOutOfRange = errors.New("value out of range")
+	// ErrNotOneOf indicates that a value is not one of the allowed values
+	ErrNotOneOf = errors.New("value is not allowed")
+	// ErrPatternMismatch indicates that a value does not match its regular expression
+	ErrPatternMismatch = errors.New("value does not match pattern")
+	// ErrInvalidURL indicates that a value is not an absolute URL
+	ErrInvalidURL = errors.New("value is not a valid url")
+	// ErrInvalidRule indicates that a validation tag cannot be used with the field
+	ErrInvalidRule = errors.New("invalid validation rule")
+)
+
+// ValidationError describes a single field that failed validation
+type ValidationError struct {
+	// Key is the environment variable name
+	Key string
+	// FullPath is the path of the field within the configuration
+	FullPath string
+	// Rule is the tag that failed, e.g. required or max
+	Rule string
+	// Err describes the violation and wraps one of the validation sentinel errors
+	Err error
+}
+
+// Error returns the ValidationError in string format
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s (%s): %s", e.Key, e.FullPath, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrors is returned by Validate and lists every violation found in a configuration
+type ValidationErrors []*ValidationError
+
+// Error returns every violation, one per line
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+
+	for _, v := range e {
+		msgs = append(msgs, v.Error())
+	}
+
+	return "envparse: invalid configuration:\n" + strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the individual violations so errors.Is and errors.As inspect each of them
+func (e ValidationErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+
+	for _, v := range e {
+		errs = append(errs, v)
+	}
+
+	return errs
+}
+
+// Validate checks every field gathered by GatherEnvInfo against its validation tags and returns
+// ValidationErrors listing all violations, or nil. Rules other than required are not checked for nil
+// pointers and empty strings, slices and maps, but zero numbers and durations are checked
+//
+//	Port  int    `koanf:"port" min:"1" max:"65535"`
+//	Level string `koanf:"level" oneof:"debug,info,warn,error"`
+//	Name  string `koanf:"name" required:"true" regexp:"^[a-z-]+$"`
+//	Host  string `koanf:"host" url:"true"`
+func (c Config) Validate(prefix string, spec interface{}) error {
+	infos, err := c.GatherEnvInfo(prefix, spec)
+	if err != nil {
+		return err
+	}
+
+	return validateInfos(infos)
+}
+
+// Process applies defaults, loads the environment and validates the result, in that order
+func (c Config) Process(prefix string, spec interface{}) error {
+	if err := c.ApplyDefaults(prefix, spec); err != nil {
+		return err
+	}
+
+	if err := c.Load(prefix, spec); err != nil {
+		return err
+	}
+
+	return c.Validate(prefix, spec)
+}
+
+// validateInfos validates the current values of the variables
+func validateInfos(infos []VarInfo) error {
+	var errs ValidationErrors
+
+	for _, info := range infos {
+		errs = append(errs, validateField(info)...)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
+// validateField checks a single variable against its validation tags
+func validateField(info VarInfo) ValidationErrors {
+	var errs ValidationErrors
+
+	fail := func(rule string, err error) {
+		errs = append(errs, &ValidationError{Key: info.Key, FullPath: info.FullPath, Rule: rule, Err: err})
+	}
+
+	v := info.Field
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	if info.Required() && isEmpty(v) {
+		fail(RequiredTag, ErrRequired)
+
+		return errs
+	}
+
+	if isUnset(v) {
+		return errs
+	}
+
+	if minTag, ok := info.Tags.Lookup(MinTag); ok {
+		if err := checkBound(v, minTag, true); err != nil {
+			fail(MinTag, err)
+		}
+	}
+
+	if maxTag, ok := info.Tags.Lookup(MaxTag); ok {
+		if err := checkBound(v, maxTag, false); err != nil {
+			fail(MaxTag, err)
+		}
+	}
+
+	if oneof, ok := info.Tags.Lookup(OneOfTag); ok {
+		if err := eachValue(v, func(e reflect.Value) error { return checkOneOf(e, oneof) }); err != nil {
+			fail(OneOfTag, err)
+		}
+	}
+
+	if pattern, ok := info.Tags.Lookup(RegexpTag); ok {
+		if err := eachValue(v, func(e reflect.Value) error { return checkPattern(e, pattern) }); err != nil {
+			fail(RegexpTag, err)
+		}
+	}
+
+	if isURL, _ := strconv.ParseBool(info.Tags.Get(URLTag)); isURL {
+		if err := eachValue(v, checkURL); err != nil {
+			fail(URLTag, err)
+		}
+	}
+
+	return errs
+}
+
+// isEmpty reports whether a value is unset; nil pointers, zero values and empty slices and maps are empty
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
+
+// isUnset reports whether a value is skipped by the rules other than required; only nil pointers and
+// empty strings, slices and maps are unset, so zero numbers and durations are still checked
+func isUnset(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.String, reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	default:
+		return false
+	}
+}
+
+// eachValue calls fn for v, or for each element when v is a slice
+func eachValue(v reflect.Value, fn func(reflect.Value) error) error {
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 {
+		return fn(v)
+	}
+
+	for i := range v.Len() {
+		if err := fn(v.Index(i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkBound compares v to the bound given in a min or max tag; numbers and durations are compared by value,
+// strings, slices and maps by length
+func checkBound(v reflect.Value, tag string, isMin bool) error {
+	var (
+		cmp   int
+		bound reflect.Value
+	)
+
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		n, err := strconv.Atoi(tag)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidRule, err)
+		}
+
+		cmp = compareOrdered(v.Len(), n)
+		bound = reflect.ValueOf(n)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		bound = reflect.New(v.Type()).Elem()
+		if err := decodeValue(bound, tag); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidRule, err)
+		}
+
+		cmp = compareNumbers(v, bound)
+	default:
+		return fmt.Errorf("%w: min and max are not supported for %s", ErrInvalidRule, v.Type())
+	}
+
+	switch {
+	case isMin && cmp < 0:
+		return fmt.Errorf("%w: must be at least %v", ErrOutOfRange, bound)
+	case !isMin && cmp > 0:
+		return fmt.Errorf("%w: must be at most %v", ErrOutOfRange, bound)
+	}
+
+	return nil
+}
+
+// compareNumbers compares two numeric values of the same type
+func compareNumbers(a, b reflect.Value) int {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareOrdered(a.Int(), b.Int())
+	case reflect.Float32, reflect.Float64:
+		return compareOrdered(a.Float(), b.Float())
+	default:
+		return compareOrdered(a.Uint(), b.Uint())
+	}
+}
+
+func compareOrdered[T int | int64 | uint64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// checkOneOf reports whether v equals one of the comma-separated allowed values, decoded to the type of v
+func checkOneOf(v reflect.Value, tag string) error {
+	allowed := splitList(tag)
+
+	for _, option := range allowed {
+		o := reflect.New(v.Type()).Elem()
+		if err := decodeValue(o, option); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidRule, err)
+		}
+
+		if reflect.DeepEqual(v.Interface(), o.Interface()) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: must be one of [%s]", ErrNotOneOf, strings.Join(allowed, ", "))
+}
+
+// checkPattern reports whether the string v matches the regular expression
+func checkPattern(v reflect.Value, pattern string) error {
+	if v.Kind() != reflect.String {
+		return fmt.Errorf("%w: regexp is not supported for %s", ErrInvalidRule, v.Type())
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidRule, err)
+	}
+
+	if !re.MatchString(v.String()) {
+		return fmt.Errorf("%w: must match %s", ErrPatternMismatch, pattern)
+	}
+
+	return nil
+}
+
+// checkURL reports whether the string v is an absolute URL with a scheme and host
+func checkURL(v reflect.Value) error {
+	if v.Kind() != reflect.String {
+		return fmt.Errorf("%w: url is not supported for %s", ErrInvalidRule, v.Type())
+	}
+
+	u, err := url.Parse(v.String())
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidURL, err)
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("%w: missing scheme or host", ErrInvalidURL)
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package envparse_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theopenlane/utils/envparse"
+)
+
+type validateSpec struct {
+	Name     string        `json:"name" koanf:"name" required:"true" regexp:"^[a-z-]+$"`
+	Port     int           `json:"port" koanf:"port" min:"1" max:"65535"`
+	Ratio    float64       `json:"ratio" koanf:"ratio" max:"1"`
+	Timeout  time.Duration `json:"timeout" koanf:"timeout" min:"1s" max:"1m"`
+	Level    string        `json:"level" koanf:"level" oneof:"debug,info,warn,error"`
+	Modes    []string      `json:"modes" koanf:"modes" oneof:"read,write" min:"1"`
+	Endpoint string        `json:"endpoint" koanf:"endpoint" url:"true"`
+	Token    *string       `json:"token" koanf:"token" required:"true"`
+	Optional string        `json:"optional" koanf:"optional" min:"5"`
+}
+
+func TestValidate(t *testing.T) {
+	token := "t"
+
+	valid := validateSpec{
+		Name:     "my-app",
+		Port:     8080,
+		Ratio:    0.5,
+		Timeout:  5 * time.Second,
+		Level:    "info",
+		Modes:    []string{"read"},
+		Endpoint: "https://example.com/api",
+		Token:    &token,
+	}
+
+	require.NoError(t, testConfig().Validate("APP", &valid))
+}
+
+func TestValidateReportsEveryViolation(t *testing.T) {
+	spec := validateSpec{
+		Name:     "Not Valid",
+		Port:     70000,
+		Ratio:    1.5,
+		Timeout:  time.Hour,
+		Level:    "trace",
+		Modes:    []string{"read", "delete"},
+		Endpoint: "example.com",
+	}
+
+	err := testConfig().Validate("APP", &spec)
+	require.Error(t, err)
+
+	var verrs envparse.ValidationErrors
+	require.ErrorAs(t, err, &verrs)
+
+	got := map[string]string{}
+	for _, v := range verrs {
+		got[v.Key] = v.Rule
+	}
+
+	assert.Equal(t, map[string]string{
+		"APP_NAME":     envparse.RegexpTag,
+		"APP_PORT":     envparse.MaxTag,
+		"APP_RATIO":    envparse.MaxTag,
+		"APP_TIMEOUT":  envparse.MaxTag,
+		"APP_LEVEL":    envparse.OneOfTag,
+		"APP_MODES":    envparse.OneOfTag,
+		"APP_ENDPOINT": envparse.URLTag,
+		"APP_TOKEN":    envparse.RequiredTag,
+	}, got)
+
+	assert.ErrorIs(t, err, envparse.ErrRequired)
+	assert.ErrorIs(t, err, envparse.ErrOutOfRange)
+	assert.ErrorIs(t, err, envparse.ErrNotOneOf)
+	assert.ErrorIs(t, err, envparse.ErrPatternMismatch)
+	assert.ErrorIs(t, err, envparse.ErrInvalidURL)
+
+	var verr *envparse.ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, "app.name", verr.FullPath)
+
+	assert.Contains(t, err.Error(), "APP_TIMEOUT (app.timeout): value out of range: must be at most 1m0s")
+}
+
+func TestValidateMin(t *testing.T) {
+	spec := validateSpec{Name: "a", Port: -1, Timeout: time.Millisecond, Optional: "abc", Modes: []string{}}
+	token := ""
+	spec.Token = &token
+
+	err := testConfig().Validate("APP", &spec)
+
+	var verrs envparse.ValidationErrors
+	require.True(t, errors.As(err, &verrs))
+
+	rules := map[string]string{}
+	for _, v := range verrs {
+		rules[v.Key] = v.Rule
+	}
+
+	assert.Equal(t, map[string]string{
+		"APP_PORT":     envparse.MinTag,
+		"APP_TIMEOUT":  envparse.MinTag,
+		"APP_OPTIONAL": envparse.MinTag,
+		"APP_TOKEN":    envparse.RequiredTag,
+	}, rules)
+}
+
+func TestValidateZeroValues(t *testing.T) {
+	spec := struct {
+		Workers  int           `json:"workers" koanf:"workers" min:"1"`
+		Timeout  time.Duration `json:"timeout" koanf:"timeout" min:"1s"`
+		Priority int           `json:"priority" koanf:"priority" oneof:"1,2,3"`
+		Retries  int           `json:"retries" koanf:"retries" min:"0" max:"5"`
+		Name     string        `json:"name" koanf:"name" min:"3"`
+		Ratio    *float64      `json:"ratio" koanf:"ratio" min:"0.5"`
+	}{}
+
+	err := testConfig().Validate("APP", &spec)
+
+	var verrs envparse.ValidationErrors
+	require.ErrorAs(t, err, &verrs)
+
+	rules := map[string]string{}
+	for _, v := range verrs {
+		rules[v.Key] = v.Rule
+	}
+
+	assert.Equal(t, map[string]string{
+		"APP_WORKERS":  envparse.MinTag,
+		"APP_TIMEOUT":  envparse.MinTag,
+		"APP_PRIORITY": envparse.OneOfTag,
+	}, rules, "zero numbers are checked while empty strings and nil pointers are skipped")
+}
+
+func TestValidateInvalidRule(t *testing.T) {
+	spec := struct {
+		Enabled bool `json:"enabled" koanf:"enabled" min:"1"`
+	}{Enabled: true}
+
+	err := testConfig().Validate("APP", &spec)
+	assert.ErrorIs(t, err, envparse.ErrInvalidRule)
+}
+
+func TestProcess(t *testing.T) {
+	t.Setenv("APP_PORT", "0")
+
+	spec := struct {
+		Host string `json:"host" koanf:"host" default:"localhost" required:"true"`
+		Port int    `json:"port" koanf:"port" required:"true"`
+	}{}
+
+	err := testConfig().Process("APP", &spec)
+	require.ErrorIs(t, err, envparse.ErrRequired)
+	assert.Equal(t, "localhost", spec.Host)
+
+	t.Setenv("APP_PORT", "443")
+
+	require.NoError(t, testConfig().Process("APP", &spec))
+	assert.Equal(t, 443, spec.Port)
+}
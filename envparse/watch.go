@@ -0,0 +1,322 @@
+package envparse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// DefaultPollInterval is how often a Watcher checks its files for changes when no interval is configured
+const DefaultPollInterval = 2 * time.Second
+
+// ErrInvalidPollInterval indicates that a Watcher was configured with a poll interval that is not positive
+var ErrInvalidPollInterval = errors.New("poll interval must be positive")
+
+// FieldChange describes a single configuration variable whose value changed between two configurations
+type FieldChange struct {
+	// FullPath is the path of the field within the configuration
+	FullPath string `json:"path"`
+	// Key is the environment variable name
+	Key string `json:"key"`
+	// Old is the previous value, redacted for sensitive fields
+	Old string `json:"old"`
+	// New is the current value, redacted for sensitive fields
+	New string `json:"new"`
+}
+
+// Change is delivered to Watcher subscribers when a reload produces a different configuration
+type Change[T any] struct {
+	// Old is the previous snapshot
+	Old *T
+	// New is the snapshot now returned by Current
+	New *T
+	// Fields lists every variable that changed, keyed by FullPath
+	Fields []FieldChange
+}
+
+// Changed reports whether the variable with the given FullPath is part of the change
+func (c Change[T]) Changed(fullPath string) bool {
+	for _, f := range c.Fields {
+		if f.FullPath == fullPath {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WatchOption configures a Watcher
+type WatchOption func(*watchOptions)
+
+type watchOptions struct {
+	interval time.Duration
+	onError  func(error)
+	signals  bool
+	// ready is called by Run once it watches the files and signals
+	ready func()
+}
+
+// WithPollInterval sets how often the configuration files are checked for changes; it must be positive
+func WithPollInterval(d time.Duration) WatchOption {
+	return func(o *watchOptions) {
+		o.interval = d
+	}
+}
+
+// WithErrorHandler sets the function called with the error of a rejected reload
+func WithErrorHandler(fn func(error)) WatchOption {
+	return func(o *watchOptions) {
+		o.onError = fn
+	}
+}
+
+// WithoutSignal disables reloading the configuration on SIGHUP
+func WithoutSignal() WatchOption {
+	return func(o *watchOptions) {
+		o.signals = false
+	}
+}
+
+// Watcher keeps an up to date snapshot of a configuration loaded by a Loader, reloading it when one of
+// the Loader's files changes or the process receives SIGHUP. A reload that fails to decode or validate
+// is rejected and the previous snapshot is kept
+//
+//	w, err := envparse.NewWatcher[Config](loader)
+//	...
+//	w.Subscribe(func(c envparse.Change[Config]) {
+//		if c.Changed("core.loglevel") {
+//			setLevel(c.New.LogLevel)
+//		}
+//	})
+//
+//	go w.Run(ctx)
+type Watcher[T any] struct {
+	loader  Loader
+	opts    watchOptions
+	current atomic.Pointer[T]
+
+	// reloadMu serializes reloads, mu guards the subscribers
+	reloadMu sync.Mutex
+
+	mu     sync.Mutex
+	subs   map[int]func(Change[T])
+	nextID int
+}
+
+// NewWatcher loads the initial configuration with loader and returns a Watcher for it; an error is
+// returned if the initial configuration or the options are invalid
+func NewWatcher[T any](loader Loader, opts ...WatchOption) (*Watcher[T], error) {
+	w := &Watcher[T]{
+		loader: loader,
+		opts:   watchOptions{interval: DefaultPollInterval, signals: true},
+		subs:   map[int]func(Change[T]){},
+	}
+
+	for _, opt := range opts {
+		opt(&w.opts)
+	}
+
+	if w.opts.interval <= 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPollInterval, w.opts.interval)
+	}
+
+	cfg := new(T)
+	if _, err := loader.Load(cfg); err != nil {
+		return nil, err
+	}
+
+	w.current.Store(cfg)
+
+	return w, nil
+}
+
+// Current returns the latest valid snapshot; snapshots are shared and must not be modified
+func (w *Watcher[T]) Current() *T {
+	return w.current.Load()
+}
+
+// Subscribe registers fn to be called after every reload that changes the configuration and returns
+// a function that removes the subscription
+func (w *Watcher[T]) Subscribe(fn func(Change[T])) func() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	id := w.nextID
+	w.nextID++
+	w.subs[id] = fn
+
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		delete(w.subs, id)
+	}
+}
+
+// Reload loads a new snapshot, swaps it in if it is valid and notifies subscribers of the fields that
+// changed; the error of an invalid configuration is returned and the current snapshot is kept
+func (w *Watcher[T]) Reload() error {
+	// serialize reloads so subscribers observe changes in order
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	next := new(T)
+	if _, err := w.loader.Load(next); err != nil {
+		return err
+	}
+
+	prev := w.current.Load()
+
+	fields, err := w.diff(prev, next)
+	if err != nil {
+		return err
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	w.current.Store(next)
+
+	w.mu.Lock()
+	subs := make([]func(Change[T]), 0, len(w.subs))
+
+	for _, fn := range w.subs {
+		subs = append(subs, fn)
+	}
+	w.mu.Unlock()
+
+	change := Change[T]{Old: prev, New: next, Fields: fields}
+	for _, fn := range subs {
+		fn(change)
+	}
+
+	return nil
+}
+
+// diff returns the variables whose values differ between two snapshots; prev has been published by
+// Current and may be read concurrently, so it is only walked by GatherEnvInfo, which never writes to it
+func (w *Watcher[T]) diff(prev, next *T) ([]FieldChange, error) {
+	before, err := w.loader.Config.GatherEnvInfo(w.loader.Prefix, prev)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := w.loader.Config.GatherEnvInfo(w.loader.Prefix, next)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffInfos(before, after), nil
+}
+
+// diffInfos compares the values of two sets of variables by FullPath; variables only present in one
+// set have an empty Old or New value
+func diffInfos(before, after []VarInfo) []FieldChange {
+	old := make(map[string]VarInfo, len(before))
+	for _, info := range before {
+		old[info.FullPath] = info
+	}
+
+	current := make(map[string]bool, len(after))
+
+	var changes []FieldChange
+
+	for _, info := range after {
+		current[info.FullPath] = true
+
+		prev, ok := old[info.FullPath]
+		if ok && prev.Value() == info.Value() {
+			continue
+		}
+
+		change := FieldChange{FullPath: info.FullPath, Key: info.Key, New: info.DisplayValue()}
+		if ok {
+			change.Old = prev.DisplayValue()
+		}
+
+		changes = append(changes, change)
+	}
+
+	// variables of removed slice elements and map entries
+	for _, info := range before {
+		if !current[info.FullPath] {
+			changes = append(changes, FieldChange{FullPath: info.FullPath, Key: info.Key, Old: info.DisplayValue()})
+		}
+	}
+
+	return changes
+}
+
+// Run watches the Loader's files and SIGHUP until ctx is done, reloading the configuration whenever
+// they change; rejected reloads are passed to the error handler
+func (w *Watcher[T]) Run(ctx context.Context) error {
+	var sig chan os.Signal
+
+	if w.opts.signals {
+		sig = make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGHUP)
+
+		defer signal.Stop(sig)
+	}
+
+	ticker := time.NewTicker(w.opts.interval)
+	defer ticker.Stop()
+
+	stamps := w.fileStamps()
+
+	if w.opts.ready != nil {
+		w.opts.ready()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sig:
+			stamps = w.fileStamps()
+			w.reloadAndReport()
+		case <-ticker.C:
+			next := w.fileStamps()
+			if next == stamps {
+				continue
+			}
+
+			stamps = next
+			w.reloadAndReport()
+		}
+	}
+}
+
+// reloadAndReport calls Reload and reports a rejected configuration to the error handler
+func (w *Watcher[T]) reloadAndReport() {
+	if err := w.Reload(); err != nil && w.opts.onError != nil {
+		w.opts.onError(err)
+	}
+}
+
+// fileStamps returns a fingerprint of the modification time and size of every watched file
+func (w *Watcher[T]) fileStamps() string {
+	var stamps []byte
+
+	for _, path := range w.loader.Files {
+		fi, err := os.Stat(path)
+		if err != nil {
+			stamps = append(stamps, "missing;"...)
+
+			continue
+		}
+
+		stamps = fi.ModTime().AppendFormat(stamps, time.RFC3339Nano)
+		stamps = append(strconv.AppendInt(append(stamps, ' '), fi.Size(), 10), ';')
+	}
+
+	return string(stamps)
+}
@@ -0,0 +1,219 @@
+package envparse_test
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theopenlane/utils/envparse"
+)
+
+type watchSpec struct {
+	Level  string `json:"level" koanf:"level" default:"info" oneof:"debug,info,warn"`
+	Limit  int    `json:"limit" koanf:"limit" min:"1"`
+	Secret string `json:"secret" koanf:"secret" sensitive:"true"`
+}
+
+func newTestWatcher(t *testing.T, content string, opts ...envparse.WatchOption) (*envparse.Watcher[watchSpec], string) {
+	t.Helper()
+
+	path := writeFile(t, "config.yaml", content)
+
+	loader := envparse.Loader{
+		Config: testConfig(),
+		Prefix: "APP",
+		Files:  []string{path},
+		Lookup: envparse.MapLookup(nil),
+	}
+
+	w, err := envparse.NewWatcher[watchSpec](loader, opts...)
+	require.NoError(t, err)
+
+	return w, path
+}
+
+func TestWatcherReload(t *testing.T) {
+	w, path := newTestWatcher(t, "limit: 10\nsecret: a\n")
+
+	assert.Equal(t, 10, w.Current().Limit)
+	assert.Equal(t, "info", w.Current().Level)
+
+	var changes []envparse.Change[watchSpec]
+
+	unsubscribe := w.Subscribe(func(c envparse.Change[watchSpec]) {
+		changes = append(changes, c)
+	})
+
+	// no change, no notification
+	require.NoError(t, w.Reload())
+	assert.Empty(t, changes)
+
+	require.NoError(t, os.WriteFile(path, []byte("limit: 20\nlevel: debug\nsecret: b\n"), 0o600))
+	require.NoError(t, w.Reload())
+
+	require.Len(t, changes, 1)
+	assert.Equal(t, 10, changes[0].Old.Limit)
+	assert.Equal(t, 20, changes[0].New.Limit)
+	assert.True(t, changes[0].Changed("app.limit"))
+	assert.Equal(t, []envparse.FieldChange{
+		{FullPath: "app.level", Key: "APP_LEVEL", Old: "info", New: "debug"},
+		{FullPath: "app.limit", Key: "APP_LIMIT", Old: "10", New: "20"},
+		{FullPath: "app.secret", Key: "APP_SECRET", Old: envparse.RedactedValue, New: envparse.RedactedValue},
+	}, changes[0].Fields)
+	assert.Equal(t, 20, w.Current().Limit)
+
+	unsubscribe()
+
+	require.NoError(t, os.WriteFile(path, []byte("limit: 30\n"), 0o600))
+	require.NoError(t, w.Reload())
+	assert.Len(t, changes, 1)
+	assert.Equal(t, 30, w.Current().Limit)
+}
+
+func TestWatcherRejectsInvalidConfig(t *testing.T) {
+	w, path := newTestWatcher(t, "limit: 10\n")
+
+	notified := false
+
+	w.Subscribe(func(envparse.Change[watchSpec]) { notified = true })
+
+	require.NoError(t, os.WriteFile(path, []byte("limit: 0\nlevel: trace\n"), 0o600))
+
+	err := w.Reload()
+	require.ErrorIs(t, err, envparse.ErrNotOneOf)
+
+	assert.False(t, notified)
+	assert.Equal(t, 10, w.Current().Limit)
+	assert.Equal(t, "info", w.Current().Level)
+}
+
+func TestNewWatcherInvalidInitialConfig(t *testing.T) {
+	loader := envparse.Loader{
+		Config: testConfig(),
+		Prefix: "APP",
+		Files:  []string{writeFile(t, "config.yaml", "limit: 0\nlevel: trace\n")},
+		Lookup: envparse.MapLookup(nil),
+	}
+
+	_, err := envparse.NewWatcher[watchSpec](loader)
+	assert.Error(t, err)
+}
+
+func TestNewWatcherInvalidPollInterval(t *testing.T) {
+	loader := envparse.Loader{
+		Config: testConfig(),
+		Prefix: "APP",
+		Files:  []string{writeFile(t, "config.yaml", "limit: 1\n")},
+		Lookup: envparse.MapLookup(nil),
+	}
+
+	for _, interval := range []time.Duration{0, -time.Second} {
+		_, err := envparse.NewWatcher[watchSpec](loader, envparse.WithPollInterval(interval))
+		assert.ErrorIs(t, err, envparse.ErrInvalidPollInterval)
+	}
+}
+
+func TestWatcherRunPollsFiles(t *testing.T) {
+	errs := make(chan error, 1)
+
+	ready := make(chan struct{})
+
+	w, path := newTestWatcher(t, "limit: 1\n",
+		envparse.WithPollInterval(10*time.Millisecond),
+		envparse.WithErrorHandler(func(err error) { errs <- err }),
+		envparse.WithoutSignal(),
+		envparse.WithReadyHook(func() { close(ready) }),
+	)
+
+	changed := make(chan envparse.Change[watchSpec], 1)
+	w.Subscribe(func(c envparse.Change[watchSpec]) { changed <- c })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		_ = w.Run(ctx)
+	}()
+
+	// let the watcher take its first fingerprint before changing the file
+	<-ready
+	require.NoError(t, os.WriteFile(path, []byte("limit: 200\n"), 0o600))
+
+	select {
+	case c := <-changed:
+		assert.Equal(t, 200, c.New.Limit)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for reload")
+	}
+
+	require.NoError(t, os.WriteFile(path, []byte("limit: -5\n"), 0o600))
+
+	select {
+	case err := <-errs:
+		assert.ErrorIs(t, err, envparse.ErrOutOfRange)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for rejected reload")
+	}
+
+	assert.Equal(t, 200, w.Current().Limit)
+
+	cancel()
+	<-done
+}
+
+type watchCollectionSpec struct {
+	Limit     int                 `json:"limit" koanf:"limit"`
+	Providers map[string]provider `json:"providers" koanf:"providers"`
+}
+
+func TestWatcherConcurrentReads(t *testing.T) {
+	env := map[string]string{"APP_PROVIDERS_GITHUB_NAME": "gh"}
+	path := writeFile(t, "config.yaml", "limit: 0\n")
+
+	loader := envparse.Loader{
+		Config:  testConfig(),
+		Prefix:  "APP",
+		Files:   []string{path},
+		Lookup:  envparse.MapLookup(env),
+		Environ: []string{"APP_PROVIDERS_GITHUB_NAME=gh"},
+	}
+
+	w, err := envparse.NewWatcher[watchCollectionSpec](loader)
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	read := make(chan struct{})
+
+	go func() {
+		defer close(read)
+
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				_ = w.Current().Providers["github"].Name
+			}
+		}
+	}()
+
+	for i := range 50 {
+		require.NoError(t, os.WriteFile(path, []byte("limit: "+strconv.Itoa(i)+"\n"), 0o600))
+		require.NoError(t, w.Reload())
+	}
+
+	close(done)
+	<-read
+
+	assert.Equal(t, 49, w.Current().Limit)
+	assert.Equal(t, "gh", w.Current().Providers["github"].Name)
+}
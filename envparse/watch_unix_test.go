@@ -0,0 +1,54 @@
+//go:build unix
+
+package envparse_test
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theopenlane/utils/envparse"
+)
+
+func TestWatcherRunSIGHUP(t *testing.T) {
+	ready := make(chan struct{})
+
+	w, path := newTestWatcher(t, "limit: 1\n",
+		envparse.WithPollInterval(time.Hour),
+		envparse.WithReadyHook(func() { close(ready) }),
+	)
+
+	changed := make(chan envparse.Change[watchSpec], 1)
+	w.Subscribe(func(c envparse.Change[watchSpec]) { changed <- c })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		_ = w.Run(ctx)
+	}()
+
+	// SIGHUP terminates the test binary unless Run has registered for it
+	<-ready
+	require.NoError(t, os.WriteFile(path, []byte("limit: 2\n"), 0o600))
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+
+	select {
+	case c := <-changed:
+		assert.Equal(t, 2, c.New.Limit)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for SIGHUP reload")
+	}
+
+	cancel()
+	<-done
+}
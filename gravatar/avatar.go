@@ -0,0 +1,425 @@
+package gravatar
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// AvatarStyle selects how a locally generated avatar looks
+type AvatarStyle string
+
+const (
+	// StyleIdenticon draws a symmetric 5x5 pattern
+	StyleIdenticon AvatarStyle = "identicon"
+	// StyleInitials draws up to two initials on a coloured background
+	StyleInitials AvatarStyle = "initials"
+)
+
+// AvatarFormat is the image format of a locally generated avatar
+type AvatarFormat string
+
+const (
+	// FormatPNG encodes avatars as PNG images
+	FormatPNG AvatarFormat = "png"
+	// FormatSVG encodes avatars as SVG documents
+	FormatSVG AvatarFormat = "svg"
+)
+
+// avatarCacheControl is sent with generated avatars, which never change for the same request
+const avatarCacheControl = "public, max-age=86400"
+
+var (
+	// ErrInvalidAvatarKey is returned when an avatar key is neither an email address nor an MD5 or SHA-256 hex hash
+	ErrInvalidAvatarKey = errors.New("avatar key must be an email address or a hex encoded md5 or sha256 hash")
+	// ErrInvalidAvatarStyle is returned for a style other than identicon or initials
+	ErrInvalidAvatarStyle = errors.New("avatar style must be identicon or initials")
+	// ErrInvalidAvatarFormat is returned for a format other than png or svg
+	ErrInvalidAvatarFormat = errors.New("avatar format must be png or svg")
+)
+
+// AvatarOptions configures a locally generated avatar
+type AvatarOptions struct {
+	// Style of the avatar, defaults to StyleIdenticon
+	Style AvatarStyle
+	// Format of the image, defaults to FormatPNG
+	Format AvatarFormat
+	// Size of the image square in pixels, defaults to 80 and may be up to 2048
+	Size int
+	// Name is used for the initials; without it the initials are a question mark, whatever the key
+	Name string
+	// Hash selects how email addresses are hashed, defaults to SHA256
+	Hash HashAlgorithm
+}
+
+// GenerateAvatar writes an avatar for key without contacting Gravatar. The key is an email address or
+// its MD5 or SHA-256 hex hash, and the same key always produces the same image; colours and the
+// identicon pattern are derived from the hash and initials only from Name, never from the key, so an
+// email address and its hash give the same avatar in every style. Callers holding the email address
+// can pass it as Name. PNG initials support the letters A-Z and digits, other
+// characters are drawn as a question mark
+func GenerateAvatar(w io.Writer, key string, opts *AvatarOptions) error {
+	o, err := opts.withDefaults()
+	if err != nil {
+		return err
+	}
+
+	sum, err := avatarHash(key, o.Hash)
+	if err != nil {
+		return err
+	}
+
+	initials := AvatarInitials(o.Name)
+	if initials == "" {
+		initials = "?"
+	}
+
+	switch {
+	case o.Style == StyleIdenticon && o.Format == FormatPNG:
+		return png.Encode(w, identiconImage(sum, o.Size))
+	case o.Style == StyleIdenticon:
+		_, err = io.WriteString(w, identiconSVG(sum, o.Size))
+	case o.Format == FormatPNG:
+		return png.Encode(w, initialsImage(sum, initials, o.Size))
+	default:
+		_, err = io.WriteString(w, initialsSVG(sum, initials, o.Size))
+	}
+
+	return err
+}
+
+// AvatarInitials returns up to two uppercase initials of a name or the local part of an email address,
+// taken from the first and last words
+func AvatarInitials(name string) string {
+	name, _, _ = strings.Cut(name, "@")
+
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune("._-+", r)
+	})
+
+	var initials []rune
+
+	for i, word := range words {
+		if i != 0 && i != len(words)-1 {
+			continue
+		}
+
+		for _, r := range word {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				initials = append(initials, unicode.ToUpper(r))
+
+				break
+			}
+		}
+	}
+
+	return string(initials)
+}
+
+// AvatarHandler returns an http.Handler serving generated avatars at .../{key}.png or .../{key}.svg,
+// where key is an MD5 or SHA-256 hex hash. The style, size and name can be set with the style, s and
+// name query parameters, otherwise defaults are used; responses carry an ETag and Cache-Control header.
+// Initials avatars are a question mark without a name, and as the name ends up in access logs along
+// with the URL, pass only the initials, e.g. name=S+F
+//
+//	mux.Handle("/avatars/", http.StripPrefix("/avatars", gravatar.AvatarHandler(nil)))
+func AvatarHandler(defaults *AvatarOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		opts := AvatarOptions{}
+		if defaults != nil {
+			opts = *defaults
+		}
+
+		name := path.Base(r.URL.Path)
+
+		key := strings.TrimSuffix(name, path.Ext(name))
+		if ext := strings.TrimPrefix(path.Ext(name), "."); ext != "" {
+			opts.Format = AvatarFormat(ext)
+		}
+
+		if strings.Contains(key, "@") {
+			// only hashes are served so that email addresses do not end up in access logs
+			http.Error(w, ErrInvalidAvatarKey.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		query := r.URL.Query()
+
+		if style := query.Get("style"); style != "" {
+			opts.Style = AvatarStyle(style)
+		}
+
+		if n := query.Get("name"); n != "" {
+			opts.Name = n
+		}
+
+		if s := query.Get("s"); s != "" {
+			size, err := strconv.Atoi(s)
+			if err != nil || size < MinSize {
+				http.Error(w, ErrInvalidSize.Error(), http.StatusBadRequest)
+
+				return
+			}
+
+			opts.Size = size
+		}
+
+		var buf bytes.Buffer
+		if err := GenerateAvatar(&buf, key, &opts); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		o, _ := opts.withDefaults()
+
+		avatar := &CachedAvatar{ContentType: o.Format.contentType(), ETag: avatarETag(strings.ToLower(key), o), Data: buf.Bytes()}
+		serveAvatar(w, r, avatar, avatarCacheControl)
+	})
+}
+
+// contentType returns the MIME type of the format
+func (f AvatarFormat) contentType() string {
+	if f == FormatSVG {
+		return "image/svg+xml"
+	}
+
+	return "image/png"
+}
+
+// withDefaults validates the options and fills in defaults
+func (o *AvatarOptions) withDefaults() (AvatarOptions, error) {
+	opts := AvatarOptions{}
+	if o != nil {
+		opts = *o
+	}
+
+	if opts.Style == "" {
+		opts.Style = StyleIdenticon
+	}
+
+	if opts.Format == "" {
+		opts.Format = FormatPNG
+	}
+
+	if opts.Size == 0 {
+		opts.Size = defaultSize
+	}
+
+	switch {
+	case opts.Style != StyleIdenticon && opts.Style != StyleInitials:
+		return opts, fmt.Errorf("%w: %s", ErrInvalidAvatarStyle, opts.Style)
+	case opts.Format != FormatPNG && opts.Format != FormatSVG:
+		return opts, fmt.Errorf("%w: %s", ErrInvalidAvatarFormat, opts.Format)
+	case opts.Size < MinSize || opts.Size > MaxSize:
+		return opts, fmt.Errorf("%w: %d", ErrInvalidSize, opts.Size)
+	}
+
+	return opts, nil
+}
+
+// avatarHash returns the hash bytes of an email address or hex hash
+func avatarHash(key string, alg HashAlgorithm) ([]byte, error) {
+	if strings.Contains(key, "@") {
+		hash, err := HashEmail(key, alg)
+		if err != nil {
+			return nil, err
+		}
+
+		key = hash
+	}
+
+	sum, err := hex.DecodeString(key)
+	if err != nil || (len(sum) != 16 && len(sum) != sha256.Size) {
+		return nil, ErrInvalidAvatarKey
+	}
+
+	return sum, nil
+}
+
+// avatarETag returns a strong ETag identifying the generated image
+func avatarETag(key string, o AvatarOptions) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{key, string(o.Style), string(o.Format), strconv.Itoa(o.Size), o.Name}, "\x00")))
+
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// avatarColor derives a saturated foreground colour from the hash
+func avatarColor(sum []byte) color.RGBA {
+	hue := float64(int(sum[0])<<8|int(sum[1])) / 65536 * 360
+
+	return hslToRGB(hue, 0.55, 0.5)
+}
+
+// avatarBackground is the background of identicons
+var avatarBackground = color.RGBA{R: 0xf0, G: 0xf0, B: 0xf0, A: 0xff}
+
+// hslToRGB converts a hue in degrees and saturation and lightness between 0 and 1 to RGB
+func hslToRGB(h, s, l float64) color.RGBA {
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - c/2
+
+	var r, g, b float64
+
+	switch {
+	case h < 60:
+		r, g = c, x
+	case h < 120:
+		r, g = x, c
+	case h < 180:
+		g, b = c, x
+	case h < 240:
+		g, b = x, c
+	case h < 300:
+		r, b = x, c
+	default:
+		r, b = c, x
+	}
+
+	return color.RGBA{
+		R: uint8(math.Round((r + m) * 255)),
+		G: uint8(math.Round((g + m) * 255)),
+		B: uint8(math.Round((b + m) * 255)),
+		A: 0xff,
+	}
+}
+
+// identiconCells returns the 5x5 pattern of an identicon; the left three columns come from the hash and
+// are mirrored onto the right two
+func identiconCells(sum []byte) [5][5]bool {
+	var cells [5][5]bool
+
+	for i := range 15 {
+		on := sum[2+i/8]>>(i%8)&1 == 1
+		row, col := i/3, i%3
+
+		cells[row][col] = on
+		cells[row][4-col] = on
+	}
+
+	return cells
+}
+
+// identiconImage draws an identicon on a 12 unit grid: a one unit margin around 5 cells of 2 units
+func identiconImage(sum []byte, size int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), image.NewUniform(avatarBackground), image.Point{}, draw.Src)
+
+	fg := image.NewUniform(avatarColor(sum))
+	unit := float64(size) / 12
+
+	for row, cols := range identiconCells(sum) {
+		for col, on := range cols {
+			if !on {
+				continue
+			}
+
+			rect := image.Rect(
+				int(math.Round(unit*float64(1+2*col))), int(math.Round(unit*float64(1+2*row))),
+				int(math.Round(unit*float64(3+2*col))), int(math.Round(unit*float64(3+2*row))),
+			)
+			draw.Draw(img, rect, fg, image.Point{}, draw.Src)
+		}
+	}
+
+	return img
+}
+
+// identiconSVG draws an identicon as SVG using the same grid as identiconImage
+func identiconSVG(sum []byte, size int) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 12 12" shape-rendering="crispEdges">`, size, size)
+	fmt.Fprintf(&sb, `<rect width="12" height="12" fill="%s"/>`, hexColor(avatarBackground))
+	fmt.Fprintf(&sb, `<g fill="%s">`, hexColor(avatarColor(sum)))
+
+	for row, cols := range identiconCells(sum) {
+		for col, on := range cols {
+			if on {
+				fmt.Fprintf(&sb, `<rect x="%d" y="%d" width="2" height="2"/>`, 1+2*col, 1+2*row)
+			}
+		}
+	}
+
+	sb.WriteString(`</g></svg>`)
+
+	return sb.String()
+}
+
+// initialsImage draws the initials in white on a background coloured from the hash with the bitmap font
+func initialsImage(sum []byte, initials string, size int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), image.NewUniform(avatarColor(sum)), image.Point{}, draw.Src)
+
+	runes := []rune(initials)
+
+	// glyphs are separated by one empty column
+	width := len(runes)*(glyphWidth+1) - 1
+	scale := math.Min(float64(size)*0.45/glyphHeight, float64(size)*0.7/float64(width))
+	x0 := (float64(size) - scale*float64(width)) / 2
+	y0 := (float64(size) - scale*glyphHeight) / 2
+
+	white := image.NewUniform(color.White)
+
+	for i, r := range runes {
+		glyph, ok := glyphs[r]
+		if !ok {
+			glyph = glyphs['?']
+		}
+
+		for gy, bits := range glyph {
+			for gx := range glyphWidth {
+				if bits>>(glyphWidth-1-gx)&1 == 0 {
+					continue
+				}
+
+				left := x0 + scale*float64(i*(glyphWidth+1)+gx)
+				top := y0 + scale*float64(gy)
+
+				rect := image.Rect(
+					int(math.Round(left)), int(math.Round(top)),
+					int(math.Round(left+scale)), int(math.Round(top+scale)),
+				)
+				draw.Draw(img, rect, white, image.Point{}, draw.Src)
+			}
+		}
+	}
+
+	return img
+}
+
+// initialsSVG draws the initials as SVG text on a background coloured from the hash
+func initialsSVG(sum []byte, initials string, size int) string {
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 100 100">`+
+		`<rect width="100" height="100" fill="%s"/>`+
+		`<text x="50" y="50" dy=".35em" fill="#ffffff" font-family="Helvetica, Arial, sans-serif" font-size="40" text-anchor="middle">%s</text>`+
+		`</svg>`, size, size, hexColor(avatarColor(sum)), html.EscapeString(initials))
+}
+
+// hexColor formats a colour as #rrggbb
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
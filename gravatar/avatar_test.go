@@ -0,0 +1,128 @@
+package gravatar_test
+
+import (
+	"bytes"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theopenlane/utils/gravatar"
+)
+
+func TestGenerateAvatar(t *testing.T) {
+	email := "sfunk@theopenlane.io"
+
+	for _, style := range []gravatar.AvatarStyle{gravatar.StyleIdenticon, gravatar.StyleInitials} {
+		var first, second, byHash bytes.Buffer
+
+		opts := &gravatar.AvatarOptions{Style: style, Size: 37}
+
+		require.NoError(t, gravatar.GenerateAvatar(&first, email, opts))
+		require.NoError(t, gravatar.GenerateAvatar(&second, " SFUNK@theopenlane.io", opts))
+		assert.Equal(t, first.Bytes(), second.Bytes(), "avatars are deterministic")
+
+		img, err := png.Decode(bytes.NewReader(first.Bytes()))
+		require.NoError(t, err)
+		assert.Equal(t, 37, img.Bounds().Dx())
+		assert.Equal(t, 37, img.Bounds().Dy())
+
+		require.NoError(t, gravatar.GenerateAvatar(&byHash, gravatar.HashSHA256(email), opts))
+		assert.Equal(t, first.Bytes(), byHash.Bytes(), "emails and their hashes give the same avatar")
+	}
+
+	var other, mine bytes.Buffer
+
+	require.NoError(t, gravatar.GenerateAvatar(&mine, email, nil))
+	require.NoError(t, gravatar.GenerateAvatar(&other, "someone@theopenlane.io", nil))
+	assert.NotEqual(t, mine.Bytes(), other.Bytes())
+}
+
+func TestGenerateAvatarSVG(t *testing.T) {
+	var buf bytes.Buffer
+
+	email := "sarah.funk@theopenlane.io"
+
+	require.NoError(t, gravatar.GenerateAvatar(&buf, email, &gravatar.AvatarOptions{Style: gravatar.StyleInitials, Format: gravatar.FormatSVG, Size: 120, Name: email}))
+	assert.Contains(t, buf.String(), `width="120"`)
+	assert.Contains(t, buf.String(), ">SF</text>")
+
+	buf.Reset()
+
+	require.NoError(t, gravatar.GenerateAvatar(&buf, email, &gravatar.AvatarOptions{Style: gravatar.StyleInitials, Format: gravatar.FormatSVG}))
+	assert.Contains(t, buf.String(), ">?</text>", "initials never come from the key")
+
+	buf.Reset()
+
+	require.NoError(t, gravatar.GenerateAvatar(&buf, gravatar.Hash("x@y.z"), &gravatar.AvatarOptions{Style: gravatar.StyleInitials, Format: gravatar.FormatSVG, Name: "<Ann> Lee"}))
+	assert.Contains(t, buf.String(), ">AL</text>")
+
+	buf.Reset()
+
+	require.NoError(t, gravatar.GenerateAvatar(&buf, gravatar.Hash("x@y.z"), &gravatar.AvatarOptions{Format: gravatar.FormatSVG}))
+	assert.Contains(t, buf.String(), `viewBox="0 0 12 12"`)
+}
+
+func TestGenerateAvatarErrors(t *testing.T) {
+	var buf bytes.Buffer
+
+	assert.ErrorIs(t, gravatar.GenerateAvatar(&buf, "not-a-hash", nil), gravatar.ErrInvalidAvatarKey)
+	assert.ErrorIs(t, gravatar.GenerateAvatar(&buf, "a@b.c", &gravatar.AvatarOptions{Size: 4096}), gravatar.ErrInvalidSize)
+	assert.ErrorIs(t, gravatar.GenerateAvatar(&buf, "a@b.c", &gravatar.AvatarOptions{Style: "robots"}), gravatar.ErrInvalidAvatarStyle)
+	assert.ErrorIs(t, gravatar.GenerateAvatar(&buf, "a@b.c", &gravatar.AvatarOptions{Format: "gif"}), gravatar.ErrInvalidAvatarFormat)
+}
+
+func TestAvatarInitials(t *testing.T) {
+	assert.Equal(t, "SF", gravatar.AvatarInitials("Sarah Jane Funk"))
+	assert.Equal(t, "SF", gravatar.AvatarInitials("sarah.funk@theopenlane.io"))
+	assert.Equal(t, "M", gravatar.AvatarInitials("mitb"))
+	assert.Equal(t, "ÉA", gravatar.AvatarInitials("élodie (anne)"))
+	assert.Empty(t, gravatar.AvatarInitials(""))
+}
+
+func TestAvatarHandler(t *testing.T) {
+	handler := http.StripPrefix("/avatars", gravatar.AvatarHandler(&gravatar.AvatarOptions{Style: gravatar.StyleInitials}))
+	hash := gravatar.HashSHA256("sfunk@theopenlane.io")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/avatars/"+hash+".svg?s=64&name=S+F", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/svg+xml", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "public, max-age=86400", rec.Header().Get("Cache-Control"))
+	assert.Contains(t, rec.Body.String(), ">SF</text>")
+
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	req := httptest.NewRequest(http.MethodGet, "/avatars/"+hash+".svg?s=64&name=S+F", nil)
+	req.Header.Set("If-None-Match", etag)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/avatars/"+hash+".png?style=identicon", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+
+	for _, target := range []string{
+		"/avatars/sfunk@theopenlane.io.png",
+		"/avatars/" + hash + ".gif",
+		"/avatars/" + hash + ".png?s=big",
+		"/avatars/" + hash + ".png?s=0",
+	} {
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, target)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/avatars/"+hash+".png", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -2,9 +2,14 @@ package gravatar
 
 import (
 	"crypto/md5" //nolint:gosec
+	"crypto/sha256"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"net/url"
+	"path"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -14,61 +19,173 @@ const (
 	defaultSize   = 80
 	defaultImage  = "robohash"
 	defaultRating = "pg"
+
+	// MinSize is the smallest image size Gravatar serves
+	MinSize = 1
+	// MaxSize is the largest image size Gravatar serves
+	MaxSize = 2048
+)
+
+// HashAlgorithm selects how email addresses are hashed in image URLs
+type HashAlgorithm string
+
+const (
+	// SHA256 is the hash recommended by Gravatar and the default
+	SHA256 HashAlgorithm = "sha256"
+	// MD5 is the legacy hash, still accepted by Gravatar
+	MD5 HashAlgorithm = "md5"
+)
+
+var (
+	// ErrInvalidSize is returned when the size is outside MinSize and MaxSize
+	ErrInvalidSize = errors.New("gravatar size must be between 1 and 2048")
+	// ErrInvalidRating is returned for a rating other than g, pg, r or x
+	ErrInvalidRating = errors.New("gravatar rating must be one of g, pg, r or x")
+	// ErrInvalidDefaultImage is returned for an unknown default image or an unusable custom default image URL
+	ErrInvalidDefaultImage = errors.New("invalid gravatar default image")
+	// ErrInvalidHashAlgorithm is returned for a hash algorithm other than SHA256 or MD5
+	ErrInvalidHashAlgorithm = errors.New("gravatar hash algorithm must be sha256 or md5")
 )
 
 var (
 	baseURL, _ = url.Parse("https://www.gravatar.com")
+
+	// ratings are the accepted image ratings
+	ratings = []string{"g", "pg", "r", "x"}
+
+	// defaultImages are the built in default images
+	defaultImages = []string{"404", "mp", "identicon", "monsterid", "wavatar", "retro", "robohash", "blank", "initials", "color"}
+
+	// defaultImageExtensions are the image extensions Gravatar accepts for custom default images
+	defaultImageExtensions = []string{".jpg", ".jpeg", ".gif", ".png", ".heic"}
 )
 
-// New returns a Gravatar image URL for the given email address
+// New returns a Gravatar image URL for the given email address. It is best effort and always returns
+// a URL: invalid options, such as a size over MaxSize, are replaced by their defaults of a size of 80,
+// the robohash default image, the pg rating and SHA-256 hashing. Use URL to reject invalid options
 func New(email string, opts *Options) string {
+	link, _ := URL(email, opts.withDefaults())
+
+	return link
+}
+
+// withDefaults returns a copy of the options with every invalid option replaced by its default
+func (o *Options) withDefaults() *Options {
+	if o == nil {
+		return nil
+	}
+
+	fixed := *o
+
+	if !validSize(fixed.Size) {
+		fixed.Size = defaultSize
+	}
+
+	if !validRating(fixed.Rating) {
+		fixed.Rating = defaultRating
+	}
+
+	if validateDefaultImage(fixed.DefaultImage) != nil {
+		fixed.DefaultImage = defaultImage
+	}
+
+	if !validHash(fixed.Hash) {
+		fixed.Hash = SHA256
+	}
+
+	return &fixed
+}
+
+// URL returns a Gravatar image URL for the given email address after validating the options; nil
+// options use a size of 80, the robohash default image, the pg rating and SHA-256 hashing
+func URL(email string, opts *Options) (string, error) {
 	if opts == nil {
 		opts = &Options{Size: defaultSize, DefaultImage: defaultImage, Rating: defaultRating}
 	}
 
-	img := Hash(email)
+	if err := opts.Validate(); err != nil {
+		return "", err
+	}
+
+	img, err := HashEmail(email, opts.Hash)
+	if err != nil {
+		return "", err
+	}
+
 	if opts.FileExtension != "" {
 		img += opts.FileExtension
 	}
 
 	link := baseURL.ResolveReference(&url.URL{Path: filepath.Join("avatar", img)})
+	link.RawQuery = opts.query().Encode()
+
+	return link.String(), nil
+}
 
+// query returns the image URL query parameters of the options
+func (o *Options) query() url.Values {
 	params := make(url.Values)
-	if opts.Size != 0 {
-		params.Set("s", strconv.Itoa(opts.Size))
+	if o.Size != 0 {
+		params.Set("s", strconv.Itoa(o.Size))
 	}
 
-	if opts.DefaultImage != "" {
-		params.Set("d", opts.DefaultImage)
+	if o.DefaultImage != "" {
+		// custom default image URLs are URL-encoded by Encode
+		params.Set("d", defaultImageValue(o.DefaultImage))
 	}
 
-	if opts.ForceDefault {
+	if o.ForceDefault {
 		params.Set("f", "y")
 	}
 
-	if opts.Rating != "" {
-		params.Set("r", opts.Rating)
+	if o.Rating != "" {
+		params.Set("r", strings.ToLower(o.Rating))
 	}
 
-	link.RawQuery = params.Encode()
-
-	return link.String()
+	return params
 }
 
 // Hash returns the Gravatar email MD5 hex encoded hash as defined in:
 // https://en.gravatar.com/site/implement/hash/
+//
+// MD5 is kept for existing URLs, use HashSHA256 or HashEmail for new ones
 func Hash(email string) string {
-	email = strings.ToLower(strings.TrimSpace(email))
-	sum := md5.Sum([]byte(email)) // nolint: gosec
+	sum := md5.Sum([]byte(normalizeEmail(email))) // nolint: gosec
+
+	return hex.EncodeToString(sum[:])
+}
+
+// HashSHA256 returns the Gravatar email SHA-256 hex encoded hash, which is the hash recommended by
+// Gravatar and the one used by the profile API
+func HashSHA256(email string) string {
+	sum := sha256.Sum256([]byte(normalizeEmail(email)))
 
 	return hex.EncodeToString(sum[:])
 }
 
+// HashEmail returns the hash of the email address using the given algorithm, SHA256 if empty
+func HashEmail(email string, alg HashAlgorithm) (string, error) {
+	switch alg {
+	case "", SHA256:
+		return HashSHA256(email), nil
+	case MD5:
+		return Hash(email), nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrInvalidHashAlgorithm, alg)
+	}
+}
+
+// normalizeEmail trims and lowercases an email address before it is hashed
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
 type Options struct {
 	// Size of the image square; request images from 1px up to 2048px.
 	Size int
 
-	// DefaultImage is can be of 404, mp, identicon, monsterid, wavatar, retro, robohash, or blank.
+	// DefaultImage is can be of 404, mp, identicon, monsterid, wavatar, retro, robohash, blank,
+	// initials, color or the http(s) URL of a custom image.
 	DefaultImage string
 
 	// ForceDefault image to always load
@@ -79,4 +196,87 @@ type Options struct {
 
 	// FileExtension is optional, can be one of .png, .jpg, etc.
 	FileExtension string
+
+	// Hash selects how the email address is hashed, defaults to SHA256
+	Hash HashAlgorithm
+}
+
+// Validate checks the size, rating, default image and hash algorithm; zero values are valid and
+// leave the parameter out of the URL
+func (o *Options) Validate() error {
+	if !validSize(o.Size) {
+		return fmt.Errorf("%w: %d", ErrInvalidSize, o.Size)
+	}
+
+	if !validRating(o.Rating) {
+		return fmt.Errorf("%w: %s", ErrInvalidRating, o.Rating)
+	}
+
+	if err := validateDefaultImage(o.DefaultImage); err != nil {
+		return err
+	}
+
+	if !validHash(o.Hash) {
+		return fmt.Errorf("%w: %s", ErrInvalidHashAlgorithm, o.Hash)
+	}
+
+	return nil
+}
+
+// validSize reports whether the size is unset or between MinSize and MaxSize
+func validSize(size int) bool {
+	return size == 0 || (size >= MinSize && size <= MaxSize)
+}
+
+// validRating reports whether the rating is unset or one of g, pg, r or x
+func validRating(rating string) bool {
+	return rating == "" || slices.Contains(ratings, strings.ToLower(rating))
+}
+
+// validHash reports whether the hash algorithm is unset, SHA256 or MD5
+func validHash(alg HashAlgorithm) bool {
+	return alg == "" || alg == SHA256 || alg == MD5
+}
+
+// validateDefaultImage accepts a built in default image or a custom image URL that Gravatar can fetch:
+// http or https on the standard port, an image extension and no query string
+func validateDefaultImage(image string) error {
+	if image == "" || slices.Contains(defaultImages, image) {
+		return nil
+	}
+
+	u, err := url.Parse(defaultImageValue(image))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidDefaultImage, err)
+	}
+
+	switch {
+	case u.Scheme != "http" && u.Scheme != "https":
+		return fmt.Errorf("%w: %s is neither a built in image nor an http(s) URL", ErrInvalidDefaultImage, image)
+	case u.Hostname() == "":
+		return fmt.Errorf("%w: missing host", ErrInvalidDefaultImage)
+	case u.Port() != "" && u.Port() != "80" && u.Port() != "443":
+		return fmt.Errorf("%w: custom images must use a standard port", ErrInvalidDefaultImage)
+	case u.RawQuery != "" || u.User != nil:
+		return fmt.Errorf("%w: custom images may not have a query string or credentials", ErrInvalidDefaultImage)
+	case !slices.Contains(defaultImageExtensions, strings.ToLower(path.Ext(u.Path))):
+		return fmt.Errorf("%w: custom images must end in .jpg, .jpeg, .gif, .png or .heic", ErrInvalidDefaultImage)
+	}
+
+	return nil
+}
+
+// defaultImageValue decodes a custom default image URL that was passed already URL-encoded, so that it
+// is encoded exactly once in the image URL
+func defaultImageValue(image string) string {
+	lower := strings.ToLower(image)
+	if !strings.HasPrefix(lower, "http%3a") && !strings.HasPrefix(lower, "https%3a") {
+		return image
+	}
+
+	if decoded, err := url.QueryUnescape(image); err == nil {
+		return decoded
+	}
+
+	return image
 }
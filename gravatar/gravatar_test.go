@@ -3,6 +3,7 @@ package gravatar_test
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/theopenlane/utils/gravatar"
@@ -11,6 +12,9 @@ import (
 func TestGravatar(t *testing.T) {
 	email := "sfunk@theopenlane.io"
 	url := gravatar.New(email, nil)
+	require.Equal(t, "https://www.gravatar.com/avatar/27145bf10d7a9e29fcf92beb8e95eea1e487fd7bcb1ee2a4ec3eefd4f15908bc?d=robohash&r=pg&s=80", url)
+
+	url = gravatar.New(email, &gravatar.Options{Size: 80, DefaultImage: "robohash", Rating: "pg", Hash: gravatar.MD5})
 	require.Equal(t, "https://www.gravatar.com/avatar/4feb2f12c4100528b14f3bed598a9598?d=robohash&r=pg&s=80", url)
 }
 
@@ -19,4 +23,91 @@ func TestHash(t *testing.T) {
 	input := "sfunk@theopenlane.io"
 	expected := "4feb2f12c4100528b14f3bed598a9598"
 	require.Equal(t, expected, gravatar.Hash(input))
+
+	hash, err := gravatar.HashEmail(input, gravatar.MD5)
+	require.NoError(t, err)
+	require.Equal(t, expected, hash)
+
+	_, err = gravatar.HashEmail(input, "sha1")
+	require.ErrorIs(t, err, gravatar.ErrInvalidHashAlgorithm)
+}
+
+func TestURLValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		opts gravatar.Options
+		want string
+		err  error
+	}{
+		{
+			name: "zero options",
+			want: "https://www.gravatar.com/avatar/27145bf10d7a9e29fcf92beb8e95eea1e487fd7bcb1ee2a4ec3eefd4f15908bc",
+		},
+		{
+			name: "custom default image is url encoded",
+			opts: gravatar.Options{Size: 2048, DefaultImage: "https://example.com/images/avatar.png", Rating: "G"},
+			want: "https://www.gravatar.com/avatar/27145bf10d7a9e29fcf92beb8e95eea1e487fd7bcb1ee2a4ec3eefd4f15908bc?d=https%3A%2F%2Fexample.com%2Fimages%2Favatar.png&r=g&s=2048",
+		},
+		{
+			name: "already encoded default image is not encoded twice",
+			opts: gravatar.Options{DefaultImage: "https%3A%2F%2Fexample.com%2Favatar.jpg"},
+			want: "https://www.gravatar.com/avatar/27145bf10d7a9e29fcf92beb8e95eea1e487fd7bcb1ee2a4ec3eefd4f15908bc?d=https%3A%2F%2Fexample.com%2Favatar.jpg",
+		},
+		{name: "size too small", opts: gravatar.Options{Size: -1}, err: gravatar.ErrInvalidSize},
+		{name: "size too large", opts: gravatar.Options{Size: 2049}, err: gravatar.ErrInvalidSize},
+		{name: "rating", opts: gravatar.Options{Rating: "nc17"}, err: gravatar.ErrInvalidRating},
+		{name: "unknown default image", opts: gravatar.Options{DefaultImage: "kitten"}, err: gravatar.ErrInvalidDefaultImage},
+		{name: "default image scheme", opts: gravatar.Options{DefaultImage: "ftp://example.com/a.png"}, err: gravatar.ErrInvalidDefaultImage},
+		{name: "default image port", opts: gravatar.Options{DefaultImage: "https://example.com:8443/a.png"}, err: gravatar.ErrInvalidDefaultImage},
+		{name: "default image query", opts: gravatar.Options{DefaultImage: "https://example.com/a.png?v=1"}, err: gravatar.ErrInvalidDefaultImage},
+		{name: "default image extension", opts: gravatar.Options{DefaultImage: "https://example.com/avatar"}, err: gravatar.ErrInvalidDefaultImage},
+		{name: "hash algorithm", opts: gravatar.Options{Hash: "sha1"}, err: gravatar.ErrInvalidHashAlgorithm},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			url, err := gravatar.URL("sfunk@theopenlane.io", &tc.opts)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				assert.NotEmpty(t, gravatar.New("sfunk@theopenlane.io", &tc.opts), "New falls back to defaults")
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, url)
+		})
+	}
+}
+
+func TestNewFallsBackToDefaults(t *testing.T) {
+	email := "sfunk@theopenlane.io"
+
+	tests := []struct {
+		name string
+		opts gravatar.Options
+		want string
+	}{
+		{
+			name: "size",
+			opts: gravatar.Options{Size: 4096, Rating: "g"},
+			want: "https://www.gravatar.com/avatar/27145bf10d7a9e29fcf92beb8e95eea1e487fd7bcb1ee2a4ec3eefd4f15908bc?r=g&s=80",
+		},
+		{
+			name: "rating and default image",
+			opts: gravatar.Options{Size: 100, Rating: "nc17", DefaultImage: "kitten"},
+			want: "https://www.gravatar.com/avatar/27145bf10d7a9e29fcf92beb8e95eea1e487fd7bcb1ee2a4ec3eefd4f15908bc?d=robohash&r=pg&s=100",
+		},
+		{
+			name: "hash algorithm",
+			opts: gravatar.Options{Hash: "sha1"},
+			want: "https://www.gravatar.com/avatar/27145bf10d7a9e29fcf92beb8e95eea1e487fd7bcb1ee2a4ec3eefd4f15908bc",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, gravatar.New(email, &tc.opts))
+		})
+	}
 }
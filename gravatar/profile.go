@@ -0,0 +1,293 @@
+package gravatar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Default values for the profile client
+const (
+	// DefaultAPIURL is the base URL of the Gravatar v3 REST API
+	DefaultAPIURL = "https://api.gravatar.com/v3"
+	// DefaultLegacyURL is the base URL of the legacy {hash}.json profile endpoint
+	DefaultLegacyURL = "https://www.gravatar.com"
+	// DefaultTimeout bounds each profile request when no HTTP client is configured
+	DefaultTimeout = 10 * time.Second
+
+	// maxProfileSize limits how much of a profile response is read
+	maxProfileSize = 1 << 20
+)
+
+var (
+	// ErrProfileNotFound is returned when no Gravatar profile exists for the hash
+	ErrProfileNotFound = errors.New("gravatar profile not found")
+	// ErrRateLimited is returned when Gravatar rejects the request because of rate limiting
+	ErrRateLimited = errors.New("gravatar rate limit exceeded")
+	// ErrUnexpectedStatus is returned for any other unsuccessful response
+	ErrUnexpectedStatus = errors.New("unexpected gravatar response status")
+	// ErrTimeout is returned when a profile request times out
+	ErrTimeout = errors.New("gravatar request timed out")
+)
+
+// Profile is a Gravatar profile as returned by either profile endpoint
+type Profile struct {
+	// Hash is the hash the profile was requested with
+	Hash string `json:"hash"`
+	// DisplayName is the name the user chose to display
+	DisplayName string `json:"display_name"`
+	// ProfileURL links to the public profile page
+	ProfileURL string `json:"profile_url"`
+	// AvatarURL links to the avatar image
+	AvatarURL string `json:"avatar_url"`
+	// AvatarAltText describes the avatar image
+	AvatarAltText string `json:"avatar_alt_text"`
+	// Location is the free form location of the user
+	Location string `json:"location"`
+	// Description is the bio of the user
+	Description string `json:"description"`
+	// JobTitle is the job title of the user
+	JobTitle string `json:"job_title"`
+	// Company is the company the user works for
+	Company string `json:"company"`
+	// Pronouns are the pronouns of the user
+	Pronouns string `json:"pronouns"`
+	// VerifiedAccounts lists the accounts the user verified on other services
+	VerifiedAccounts []VerifiedAccount `json:"verified_accounts"`
+}
+
+// VerifiedAccount is an account on another service linked to a Gravatar profile
+type VerifiedAccount struct {
+	// ServiceType identifies the service, e.g. github
+	ServiceType string `json:"service_type"`
+	// ServiceLabel is the display name of the service, e.g. GitHub
+	ServiceLabel string `json:"service_label"`
+	// URL links to the account
+	URL string `json:"url"`
+}
+
+// Client fetches Gravatar profiles
+type Client struct {
+	apiURL     *url.URL
+	legacyURL  *url.URL
+	httpClient *http.Client
+	apiKey     string
+}
+
+// ClientOption configures a Client
+type ClientOption func(*Client) error
+
+// WithAPIURL sets the base URL of the v3 REST API, e.g. to point the client at a stand-in server
+func WithAPIURL(rawURL string) ClientOption {
+	return func(c *Client) (err error) {
+		c.apiURL, err = url.Parse(rawURL)
+
+		return err
+	}
+}
+
+// WithLegacyURL sets the base URL of the legacy {hash}.json endpoint
+func WithLegacyURL(rawURL string) ClientOption {
+	return func(c *Client) (err error) {
+		c.legacyURL, err = url.Parse(rawURL)
+
+		return err
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for requests; its timeout replaces DefaultTimeout
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(c *Client) error {
+		c.httpClient = client
+
+		return nil
+	}
+}
+
+// WithTimeout sets the timeout of each request
+func WithTimeout(d time.Duration) ClientOption {
+	return func(c *Client) error {
+		client := *c.httpClient
+		client.Timeout = d
+		c.httpClient = &client
+
+		return nil
+	}
+}
+
+// WithAPIKey sets the API key sent as a bearer token to the v3 REST API, which raises the rate limit
+// and returns the full profile
+func WithAPIKey(key string) ClientOption {
+	return func(c *Client) error {
+		c.apiKey = key
+
+		return nil
+	}
+}
+
+// NewClient returns a profile Client using the public Gravatar endpoints unless configured otherwise
+func NewClient(opts ...ClientOption) (*Client, error) {
+	c := &Client{httpClient: &http.Client{Timeout: DefaultTimeout}}
+
+	if err := WithAPIURL(DefaultAPIURL)(c); err != nil {
+		return nil, err
+	}
+
+	if err := WithLegacyURL(DefaultLegacyURL)(c); err != nil {
+		return nil, err
+	}
+
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// Profile fetches the profile of the email address from the v3 REST API
+func (c *Client) Profile(ctx context.Context, email string) (*Profile, error) {
+	return c.ProfileByHash(ctx, HashSHA256(email))
+}
+
+// ProfileByHash fetches the profile with the given hash from the v3 REST API
+func (c *Client) ProfileByHash(ctx context.Context, hash string) (*Profile, error) {
+	link := c.apiURL.JoinPath("profiles", hash)
+
+	profile := &Profile{}
+	if err := c.get(ctx, link, c.apiKey, profile); err != nil {
+		return nil, err
+	}
+
+	if profile.Hash == "" {
+		profile.Hash = hash
+	}
+
+	return profile, nil
+}
+
+// LegacyProfile fetches the profile of the email address from the legacy {hash}.json endpoint
+func (c *Client) LegacyProfile(ctx context.Context, email string) (*Profile, error) {
+	return c.LegacyProfileByHash(ctx, HashSHA256(email))
+}
+
+// LegacyProfileByHash fetches the profile with the given hash from the legacy {hash}.json endpoint
+func (c *Client) LegacyProfileByHash(ctx context.Context, hash string) (*Profile, error) {
+	link := c.legacyURL.JoinPath(hash + ".json")
+
+	var legacy legacyResponse
+	if err := c.get(ctx, link, "", &legacy); err != nil {
+		return nil, err
+	}
+
+	if len(legacy.Entry) == 0 {
+		return nil, ErrProfileNotFound
+	}
+
+	return legacy.Entry[0].profile(hash), nil
+}
+
+// get requests link and decodes the JSON response into v
+func (c *Client) get(ctx context.Context, link *url.URL, apiKey string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		var netErr net.Error
+		if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+			return fmt.Errorf("%w: %w", ErrTimeout, err)
+		}
+
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrProfileNotFound
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("%w: %d", ErrUnexpectedStatus, resp.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, maxProfileSize)).Decode(v)
+}
+
+// legacyResponse is the document returned by the legacy {hash}.json endpoint
+type legacyResponse struct {
+	Entry []legacyEntry `json:"entry"`
+}
+
+type legacyEntry struct {
+	Hash            string          `json:"hash"`
+	ProfileURL      string          `json:"profileUrl"`
+	ThumbnailURL    string          `json:"thumbnailUrl"`
+	DisplayName     string          `json:"displayName"`
+	AboutMe         string          `json:"aboutMe"`
+	CurrentLocation string          `json:"currentLocation"`
+	JobTitle        string          `json:"job_title"`
+	Company         string          `json:"company"`
+	Pronouns        string          `json:"pronouns"`
+	Accounts        []legacyAccount `json:"accounts"`
+}
+
+type legacyAccount struct {
+	Shortname string `json:"shortname"`
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	// Verified is sent as the string "true" or "false"
+	Verified json.RawMessage `json:"verified"`
+}
+
+// profile converts a legacy entry to a Profile, keeping only verified accounts
+func (e legacyEntry) profile(hash string) *Profile {
+	p := &Profile{
+		Hash:        e.Hash,
+		DisplayName: e.DisplayName,
+		ProfileURL:  e.ProfileURL,
+		AvatarURL:   e.ThumbnailURL,
+		Location:    e.CurrentLocation,
+		Description: e.AboutMe,
+		JobTitle:    e.JobTitle,
+		Company:     e.Company,
+		Pronouns:    e.Pronouns,
+	}
+
+	if p.Hash == "" {
+		p.Hash = hash
+	}
+
+	for _, a := range e.Accounts {
+		verified, _ := strconv.Unquote(string(a.Verified))
+		if verified == "" {
+			verified = string(a.Verified)
+		}
+
+		if ok, _ := strconv.ParseBool(verified); !ok {
+			continue
+		}
+
+		p.VerifiedAccounts = append(p.VerifiedAccounts, VerifiedAccount{ServiceType: a.Shortname, ServiceLabel: a.Name, URL: a.URL})
+	}
+
+	return p
+}
@@ -0,0 +1,141 @@
+package gravatar_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theopenlane/utils/gravatar"
+	"github.com/theopenlane/utils/testutils"
+)
+
+const profileEmail = "sfunk@theopenlane.io"
+
+func TestHashSHA256(t *testing.T) {
+	expected := "27145bf10d7a9e29fcf92beb8e95eea1e487fd7bcb1ee2a4ec3eefd4f15908bc"
+	assert.Equal(t, expected, gravatar.HashSHA256(profileEmail))
+	assert.Equal(t, expected, gravatar.HashSHA256("  SFunk@TheOpenLane.io "))
+}
+
+func TestClientProfile(t *testing.T) {
+	hash := gravatar.HashSHA256(profileEmail)
+
+	httpClient, mux, server := testutils.TestServer()
+	defer server.Close()
+
+	mux.HandleFunc("/v3/profiles/"+hash, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "api.gravatar.com", r.Host)
+		assert.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+
+		fmt.Fprintf(w, `{
+			"hash": %q,
+			"display_name": "Sarah Funk",
+			"profile_url": "https://gravatar.com/sfunk",
+			"description": "building openlane",
+			"verified_accounts": [{"service_type": "github", "service_label": "GitHub", "url": "https://github.com/sfunk"}]
+		}`, hash)
+	})
+
+	client, err := gravatar.NewClient(gravatar.WithHTTPClient(httpClient), gravatar.WithAPIKey("key"))
+	require.NoError(t, err)
+
+	profile, err := client.Profile(context.Background(), profileEmail)
+	require.NoError(t, err)
+
+	assert.Equal(t, &gravatar.Profile{
+		Hash:        hash,
+		DisplayName: "Sarah Funk",
+		ProfileURL:  "https://gravatar.com/sfunk",
+		Description: "building openlane",
+		VerifiedAccounts: []gravatar.VerifiedAccount{
+			{ServiceType: "github", ServiceLabel: "GitHub", URL: "https://github.com/sfunk"},
+		},
+	}, profile)
+
+	_, err = client.Profile(context.Background(), "missing@theopenlane.io")
+	assert.ErrorIs(t, err, gravatar.ErrProfileNotFound)
+}
+
+func TestClientLegacyProfile(t *testing.T) {
+	hash := gravatar.HashSHA256(profileEmail)
+
+	httpClient, mux, server := testutils.TestServer()
+	defer server.Close()
+
+	mux.HandleFunc("/"+hash+".json", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "www.gravatar.com", r.Host)
+
+		fmt.Fprint(w, `{"entry": [{
+			"displayName": "Sarah Funk",
+			"aboutMe": "building openlane",
+			"currentLocation": "Earth",
+			"thumbnailUrl": "https://gravatar.com/avatar/abc",
+			"accounts": [
+				{"shortname": "github", "name": "GitHub", "url": "https://github.com/sfunk", "verified": "true"},
+				{"shortname": "x", "name": "X", "url": "https://x.com/sfunk", "verified": "false"}
+			]
+		}]}`)
+	})
+
+	client, err := gravatar.NewClient(gravatar.WithHTTPClient(httpClient))
+	require.NoError(t, err)
+
+	profile, err := client.LegacyProfile(context.Background(), profileEmail)
+	require.NoError(t, err)
+
+	assert.Equal(t, &gravatar.Profile{
+		Hash:        hash,
+		DisplayName: "Sarah Funk",
+		AvatarURL:   "https://gravatar.com/avatar/abc",
+		Location:    "Earth",
+		Description: "building openlane",
+		VerifiedAccounts: []gravatar.VerifiedAccount{
+			{ServiceType: "github", ServiceLabel: "GitHub", URL: "https://github.com/sfunk"},
+		},
+	}, profile)
+
+	_, err = client.LegacyProfileByHash(context.Background(), "unknown")
+	assert.ErrorIs(t, err, gravatar.ErrProfileNotFound)
+}
+
+func TestClientErrors(t *testing.T) {
+	httpClient, server := testutils.NewErrorServer("slow down", http.StatusTooManyRequests)
+	defer server.Close()
+
+	client, err := gravatar.NewClient(gravatar.WithHTTPClient(httpClient))
+	require.NoError(t, err)
+
+	_, err = client.ProfileByHash(context.Background(), "abc")
+	assert.ErrorIs(t, err, gravatar.ErrRateLimited)
+
+	httpClient, server = testutils.NewErrorServer("boom", http.StatusInternalServerError)
+	defer server.Close()
+
+	client, err = gravatar.NewClient(gravatar.WithHTTPClient(httpClient))
+	require.NoError(t, err)
+
+	_, err = client.ProfileByHash(context.Background(), "abc")
+	assert.ErrorIs(t, err, gravatar.ErrUnexpectedStatus)
+
+	slow := testutils.NewTestServerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+	defer slow.Close()
+
+	client, err = gravatar.NewClient(gravatar.WithAPIURL(slow.URL), gravatar.WithTimeout(20*time.Millisecond))
+	require.NoError(t, err)
+
+	_, err = client.ProfileByHash(context.Background(), "abc")
+	assert.ErrorIs(t, err, gravatar.ErrTimeout)
+
+	_, err = gravatar.NewClient(gravatar.WithAPIURL("://bad"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,387 @@
+package gravatar
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default values for the avatar proxy
+const (
+	// DefaultProxyTTL is how long proxied avatars are cached when the upstream allows it
+	DefaultProxyTTL = 24 * time.Hour
+	// DefaultMaxAvatarBytes is the largest upstream image the proxy accepts
+	DefaultMaxAvatarBytes = 1 << 20
+
+	// fallbackCacheControl keeps browsers from holding on to fallback images for long
+	fallbackCacheControl = "public, max-age=60"
+	// notFoundTTL is the longest time a missing avatar is cached, as it may be added at any time
+	notFoundTTL = 5 * time.Minute
+)
+
+var (
+	// ErrAvatarTooLarge is reported when an upstream image exceeds the size limit
+	ErrAvatarTooLarge = errors.New("avatar exceeds the maximum size")
+	// ErrAvatarContentType is reported when an upstream response is not an allowed image type
+	ErrAvatarContentType = errors.New("avatar content type not allowed")
+)
+
+// defaultAvatarContentTypes are the image types proxied by default; SVG is excluded as it can carry scripts
+var defaultAvatarContentTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+// Proxy is an http.Handler serving Gravatar images by hash from our own origin, so browsers never
+// contact gravatar.com. Images are cached in an AvatarStore and a fallback image is served when the
+// upstream request fails
+//
+//	proxy, err := gravatar.NewProxy(gravatar.WithStore(gravatar.NewRedisStore(cache.New(cfg), "avatar:")))
+//	...
+//	mux.Handle("/avatars/", http.StripPrefix("/avatars", proxy))
+//
+// Requests have the form .../{hash}?s=80&r=pg&d=identicon, with the same parameters as image URLs. Custom
+// default image URLs are never fetched, the fallback image is served in their place, and d=404 answers
+// 404 Not Found for hashes without an avatar
+type Proxy struct {
+	upstream     *url.URL
+	client       *http.Client
+	store        AvatarStore
+	ttl          time.Duration
+	maxBytes     int64
+	contentTypes []string
+	fallback     *CachedAvatar
+	onError      func(error)
+}
+
+// ProxyOption configures a Proxy
+type ProxyOption func(*Proxy) error
+
+// WithUpstreamURL sets the URL avatar hashes are appended to, https://www.gravatar.com/avatar by default
+func WithUpstreamURL(rawURL string) ProxyOption {
+	return func(p *Proxy) (err error) {
+		p.upstream, err = url.Parse(rawURL)
+
+		return err
+	}
+}
+
+// WithUpstreamClient sets the HTTP client used to fetch avatars; the Proxy uses a copy of it that never
+// follows redirects
+func WithUpstreamClient(client *http.Client) ProxyOption {
+	return func(p *Proxy) error {
+		p.client = client
+
+		return nil
+	}
+}
+
+// WithStore sets where avatars are cached, a MemoryStore of 1000 avatars by default
+func WithStore(store AvatarStore) ProxyOption {
+	return func(p *Proxy) error {
+		p.store = store
+
+		return nil
+	}
+}
+
+// WithTTL sets the longest time an avatar is cached; a shorter max-age sent by the upstream wins
+func WithTTL(ttl time.Duration) ProxyOption {
+	return func(p *Proxy) error {
+		p.ttl = ttl
+
+		return nil
+	}
+}
+
+// WithMaxBytes sets the largest upstream image accepted
+func WithMaxBytes(n int64) ProxyOption {
+	return func(p *Proxy) error {
+		p.maxBytes = n
+
+		return nil
+	}
+}
+
+// WithContentTypes sets the image types accepted from the upstream
+func WithContentTypes(types ...string) ProxyOption {
+	return func(p *Proxy) error {
+		p.contentTypes = types
+
+		return nil
+	}
+}
+
+// WithFallbackImage sets the image served when the upstream fails; by default a locally generated
+// identicon of the hash is served
+func WithFallbackImage(contentType string, data []byte) ProxyOption {
+	return func(p *Proxy) error {
+		sum := sha256.Sum256(data)
+		p.fallback = &CachedAvatar{ContentType: contentType, ETag: `"` + hex.EncodeToString(sum[:16]) + `"`, Data: data}
+
+		return nil
+	}
+}
+
+// WithProxyErrorHandler sets a function called with upstream and store errors, which are otherwise
+// only visible as fallback images and cache misses
+func WithProxyErrorHandler(fn func(error)) ProxyOption {
+	return func(p *Proxy) error {
+		p.onError = fn
+
+		return nil
+	}
+}
+
+// NewProxy returns an avatar Proxy
+func NewProxy(opts ...ProxyOption) (*Proxy, error) {
+	p := &Proxy{
+		upstream:     baseURL.JoinPath("avatar"),
+		client:       &http.Client{Timeout: DefaultTimeout},
+		store:        NewMemoryStore(1000), //nolint:mnd
+		ttl:          DefaultProxyTTL,
+		maxBytes:     DefaultMaxAvatarBytes,
+		contentTypes: defaultAvatarContentTypes,
+	}
+
+	for _, opt := range opts {
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+
+	// Gravatar redirects to custom default images, which must never be fetched from our network
+	client := *p.client
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	p.client = &client
+
+	return p, nil
+}
+
+// ServeHTTP serves the avatar of the hash in the last path segment
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	name := path.Base(r.URL.Path)
+	hash := strings.ToLower(strings.TrimSuffix(name, path.Ext(name)))
+
+	if _, err := avatarHash(hash, ""); err != nil || strings.Contains(hash, "@") {
+		http.Error(w, ErrInvalidAvatarKey.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	opts, err := proxyOptions(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	// custom default images are requested as d=404, so the upstream never redirects to them
+	upstream := *opts
+	if opts.DefaultImage != "" && !slices.Contains(defaultImages, opts.DefaultImage) {
+		upstream.DefaultImage = "404"
+	}
+
+	query := upstream.query().Encode()
+	key := hash + "?" + query
+
+	avatar, err := p.store.Get(r.Context(), key)
+	if err != nil {
+		if !errors.Is(err, ErrCacheMiss) {
+			p.report(err)
+		}
+
+		avatar, err = p.fetch(r.Context(), hash, query, upstream.DefaultImage == "404")
+		if err != nil {
+			p.report(err)
+			p.serveFallback(w, r, hash, opts.Size)
+
+			return
+		}
+
+		if avatar.Expires.After(time.Now()) {
+			if err := p.store.Set(r.Context(), key, avatar); err != nil {
+				p.report(err)
+			}
+		}
+	}
+
+	cacheControl := "no-store"
+	if maxAge := int(time.Until(avatar.Expires).Seconds()); maxAge > 0 {
+		cacheControl = "public, max-age=" + strconv.Itoa(maxAge)
+	}
+
+	if avatar.NotFound {
+		if opts.DefaultImage != "404" {
+			p.serveFallback(w, r, hash, opts.Size)
+
+			return
+		}
+
+		w.Header().Set("Cache-Control", cacheControl)
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+
+		return
+	}
+
+	serveAvatar(w, r, avatar, cacheControl)
+}
+
+// proxyOptions reads and validates the image parameters of a proxy request
+func proxyOptions(q url.Values) (*Options, error) {
+	opts := &Options{Rating: q.Get("r"), DefaultImage: q.Get("d"), ForceDefault: q.Get("f") == "y"}
+
+	if s := q.Get("s"); s != "" {
+		size, err := strconv.Atoi(s)
+		if err != nil || size == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidSize, s)
+		}
+
+		opts.Size = size
+	}
+
+	return opts, opts.Validate()
+}
+
+// fetch requests an avatar from the upstream and checks its size and content type. When notFound is
+// set, as for d=404 requests, a 404 response is returned as a briefly cached NotFound avatar
+func (p *Proxy) fetch(ctx context.Context, hash, query string, notFound bool) (*CachedAvatar, error) {
+	link := p.upstream.JoinPath(hash)
+	link.RawQuery = query
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if notFound && resp.StatusCode == http.StatusNotFound {
+		ttl := min(cacheTTL(resp.Header.Get("Cache-Control"), p.ttl), notFoundTTL)
+
+		return &CachedAvatar{NotFound: true, Expires: time.Now().Add(ttl)}, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %d", ErrUnexpectedStatus, resp.StatusCode)
+	}
+
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !slices.Contains(p.contentTypes, contentType) {
+		return nil, fmt.Errorf("%w: %s", ErrAvatarContentType, contentType)
+	}
+
+	if resp.ContentLength > p.maxBytes {
+		return nil, ErrAvatarTooLarge
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, p.maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > p.maxBytes {
+		return nil, ErrAvatarTooLarge
+	}
+
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		sum := sha256.Sum256(data)
+		etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+	}
+
+	return &CachedAvatar{
+		ContentType: contentType,
+		ETag:        etag,
+		Data:        data,
+		Expires:     time.Now().Add(cacheTTL(resp.Header.Get("Cache-Control"), p.ttl)),
+	}, nil
+}
+
+// cacheTTL returns how long a response may be cached: no time for no-store, no-cache or private
+// responses, otherwise the smaller of its max-age and limit
+func cacheTTL(cacheControl string, limit time.Duration) time.Duration {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+
+		switch {
+		case directive == "no-store" || directive == "no-cache" || directive == "private":
+			return 0
+		case strings.HasPrefix(directive, "max-age="):
+			seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+			if err == nil {
+				limit = min(limit, time.Duration(seconds)*time.Second)
+			}
+		}
+	}
+
+	return limit
+}
+
+// serveFallback serves the configured fallback image or an identicon of the hash
+func (p *Proxy) serveFallback(w http.ResponseWriter, r *http.Request, hash string, size int) {
+	avatar := p.fallback
+
+	if avatar == nil {
+		var buf bytes.Buffer
+		if err := GenerateAvatar(&buf, hash, &AvatarOptions{Size: size}); err != nil {
+			http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+
+			return
+		}
+
+		avatar = &CachedAvatar{ContentType: FormatPNG.contentType(), ETag: avatarETag(hash, AvatarOptions{Size: size}), Data: buf.Bytes()}
+	}
+
+	serveAvatar(w, r, avatar, fallbackCacheControl)
+}
+
+// serveAvatar writes an avatar, answering conditional requests with 304 Not Modified
+func serveAvatar(w http.ResponseWriter, r *http.Request, avatar *CachedAvatar, cacheControl string) {
+	w.Header().Set("ETag", avatar.ETag)
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if match := r.Header.Get("If-None-Match"); match != "" && strings.Contains(match, avatar.ETag) {
+		w.WriteHeader(http.StatusNotModified)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", avatar.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(avatar.Data)))
+
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	_, _ = w.Write(avatar.Data)
+}
+
+// report passes an error to the error handler, if one is set
+func (p *Proxy) report(err error) {
+	if p.onError != nil {
+		p.onError(err)
+	}
+}
@@ -0,0 +1,335 @@
+package gravatar_test
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theopenlane/utils/gravatar"
+	"github.com/theopenlane/utils/testutils"
+)
+
+var pngBytes = []byte("\x89PNG\r\n\x1a\nfake image")
+
+func newTestProxy(t *testing.T, handler http.HandlerFunc, opts ...gravatar.ProxyOption) (http.Handler, *atomic.Int32) {
+	t.Helper()
+
+	calls := &atomic.Int32{}
+
+	client, mux, server := testutils.TestServer()
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/avatar/", func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		handler(w, r)
+	})
+
+	proxy, err := gravatar.NewProxy(append([]gravatar.ProxyOption{gravatar.WithUpstreamClient(client)}, opts...)...)
+	require.NoError(t, err)
+
+	return http.StripPrefix("/avatars", proxy), calls
+}
+
+func serveImage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("ETag", `"upstream"`)
+	w.Header().Set("Cache-Control", "max-age=300")
+	_, _ = w.Write(pngBytes)
+}
+
+func TestProxyCachesAvatars(t *testing.T) {
+	hash := gravatar.HashSHA256("sfunk@theopenlane.io")
+
+	var query string
+
+	handler, calls := newTestProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/avatar/"+hash, r.URL.Path)
+		query = r.URL.RawQuery
+
+		serveImage(w, r)
+	})
+
+	for range 2 {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/avatars/"+hash+"?s=64&d=identicon", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, pngBytes, rec.Body.Bytes())
+		assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+		assert.Equal(t, `"upstream"`, rec.Header().Get("ETag"))
+		assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+		assert.True(t, strings.HasPrefix(rec.Header().Get("Cache-Control"), "public, max-age="))
+	}
+
+	assert.Equal(t, int32(1), calls.Load(), "second request is served from the cache")
+	assert.Equal(t, "d=identicon&s=64", query)
+
+	req := httptest.NewRequest(http.MethodGet, "/avatars/"+hash+"?s=64&d=identicon", nil)
+	req.Header.Set("If-None-Match", `"upstream"`)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.Bytes())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/avatars/"+hash+"?s=128", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, int32(2), calls.Load(), "other parameters are cached separately")
+}
+
+func TestProxyRespectsNoStore(t *testing.T) {
+	handler, calls := newTestProxy(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = w.Write(pngBytes)
+	})
+
+	for range 2 {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/avatars/"+gravatar.Hash("a@b.c"), nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+		assert.NotEmpty(t, rec.Header().Get("ETag"))
+	}
+
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestProxyFallback(t *testing.T) {
+	hash := gravatar.HashSHA256("sfunk@theopenlane.io")
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		err     error
+	}{
+		{
+			name:    "upstream error",
+			handler: func(w http.ResponseWriter, _ *http.Request) { http.Error(w, "boom", http.StatusInternalServerError) },
+			err:     gravatar.ErrUnexpectedStatus,
+		},
+		{
+			name: "content type",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "image/svg+xml")
+				_, _ = w.Write([]byte("<svg/>"))
+			},
+			err: gravatar.ErrAvatarContentType,
+		},
+		{
+			name: "too large",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "image/png")
+				_, _ = w.Write(bytes.Repeat([]byte("x"), 64))
+			},
+			err: gravatar.ErrAvatarTooLarge,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var reported error
+
+			handler, calls := newTestProxy(t, tc.handler,
+				gravatar.WithMaxBytes(32),
+				gravatar.WithProxyErrorHandler(func(err error) { reported = err }),
+			)
+
+			for range 2 {
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/avatars/"+hash+"?s=40", nil))
+
+				require.Equal(t, http.StatusOK, rec.Code)
+				assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+				assert.Equal(t, "public, max-age=60", rec.Header().Get("Cache-Control"))
+
+				img, err := png.Decode(rec.Body)
+				require.NoError(t, err, "the fallback is a generated identicon")
+				assert.Equal(t, 40, img.Bounds().Dx())
+			}
+
+			assert.ErrorIs(t, reported, tc.err)
+			assert.Equal(t, int32(2), calls.Load(), "fallback images are not cached")
+		})
+	}
+
+	handler, _ := newTestProxy(t, func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "boom", http.StatusBadGateway)
+	}, gravatar.WithFallbackImage("image/gif", []byte("GIF89a")))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/avatars/"+hash, nil))
+	assert.Equal(t, "image/gif", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "GIF89a", rec.Body.String())
+}
+
+func TestProxyNotFound(t *testing.T) {
+	hash := gravatar.HashSHA256("sfunk@theopenlane.io")
+
+	var reported error
+
+	handler, calls := newTestProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "404", r.URL.Query().Get("d"))
+		http.NotFound(w, r)
+	}, gravatar.WithProxyErrorHandler(func(err error) { reported = err }))
+
+	for range 2 {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/avatars/"+hash+"?d=404", nil))
+
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		assert.True(t, strings.HasPrefix(rec.Header().Get("Cache-Control"), "public, max-age="))
+	}
+
+	assert.Equal(t, int32(1), calls.Load(), "missing avatars are cached")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/avatars/"+hash+"?d=https://example.com/a.png", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code, "custom default images are replaced by the fallback")
+	assert.Equal(t, "public, max-age=60", rec.Header().Get("Cache-Control"))
+
+	_, err := png.Decode(rec.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(1), calls.Load(), "custom default requests share the d=404 cache entry")
+	assert.NoError(t, reported, "missing avatars are not errors")
+}
+
+func TestProxyDoesNotFollowRedirects(t *testing.T) {
+	hash := gravatar.HashSHA256("sfunk@theopenlane.io")
+
+	var (
+		query    string
+		internal atomic.Int32
+	)
+
+	// the test client sends every request to the upstream, so the redirect target is served there too
+	handler, _ := newTestProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/avatar/internal.png" {
+			internal.Add(1)
+			serveImage(w, r)
+
+			return
+		}
+
+		query = r.URL.RawQuery
+		http.Redirect(w, r, "http://10.0.0.1/avatar/internal.png", http.StatusFound)
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/avatars/"+hash+"?s=40&d=http://10.0.0.1/x.png", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "d=404&s=40", query, "custom default images are not sent upstream")
+	assert.Zero(t, internal.Load(), "redirects are not followed")
+
+	img, err := png.Decode(rec.Body)
+	require.NoError(t, err, "the fallback is served")
+	assert.Equal(t, 40, img.Bounds().Dx())
+}
+
+func TestProxyBadRequests(t *testing.T) {
+	handler, calls := newTestProxy(t, serveImage)
+	hash := gravatar.HashSHA256("sfunk@theopenlane.io")
+
+	for _, target := range []string{
+		"/avatars/sfunk@theopenlane.io",
+		"/avatars/not-a-hash",
+		"/avatars/" + hash + "?s=4096",
+		"/avatars/" + hash + "?r=nc17",
+		"/avatars/" + hash + "?d=kitten",
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, target)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/avatars/"+hash, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Zero(t, calls.Load())
+}
+
+func TestAvatarStores(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	disk, err := gravatar.NewDiskStore(t.TempDir())
+	require.NoError(t, err)
+
+	stores := map[string]gravatar.AvatarStore{
+		"memory": gravatar.NewMemoryStore(1),
+		"disk":   disk,
+		"redis":  gravatar.NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "avatar:"),
+	}
+
+	ctx := context.Background()
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			_, err := store.Get(ctx, "missing")
+			assert.ErrorIs(t, err, gravatar.ErrCacheMiss)
+
+			avatar := &gravatar.CachedAvatar{ContentType: "image/png", ETag: `"a"`, Data: pngBytes, Expires: time.Now().Add(time.Hour).Round(0)}
+			require.NoError(t, store.Set(ctx, "a", avatar))
+
+			got, err := store.Get(ctx, "a")
+			require.NoError(t, err)
+			assert.Equal(t, avatar.Data, got.Data)
+			assert.Equal(t, avatar.ETag, got.ETag)
+			assert.True(t, avatar.Expires.Equal(got.Expires))
+
+			expired := &gravatar.CachedAvatar{ContentType: "image/png", Data: pngBytes, Expires: time.Now().Add(-time.Second)}
+			require.NoError(t, store.Set(ctx, "b", expired))
+
+			_, err = store.Get(ctx, "b")
+			assert.ErrorIs(t, err, gravatar.ErrCacheMiss)
+		})
+	}
+
+	// the memory store holds a single avatar, so storing b evicted a
+	memory := stores["memory"]
+
+	_, err = memory.Get(ctx, "a")
+	assert.ErrorIs(t, err, gravatar.ErrCacheMiss)
+
+	require.NoError(t, memory.Set(ctx, "c", &gravatar.CachedAvatar{Expires: time.Now().Add(time.Hour)}))
+
+	_, err = memory.Get(ctx, "c")
+	assert.NoError(t, err)
+
+	mr.FastForward(2 * time.Hour)
+
+	_, err = stores["redis"].Get(ctx, "a")
+	assert.ErrorIs(t, err, gravatar.ErrCacheMiss)
+}
+
+func TestProxyWithRedisStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := gravatar.NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "avatar:")
+
+	handler, calls := newTestProxy(t, serveImage, gravatar.WithStore(store))
+	hash := gravatar.HashSHA256("sfunk@theopenlane.io")
+
+	for range 2 {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/avatars/"+hash, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Len(t, mr.Keys(), 1)
+	assert.Equal(t, 300*time.Second, mr.TTL(mr.Keys()[0]).Round(time.Minute))
+}
@@ -0,0 +1,221 @@
+package gravatar
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrCacheMiss is returned by an AvatarStore when it holds no unexpired avatar for a key
+var ErrCacheMiss = errors.New("avatar not cached")
+
+// CachedAvatar is an avatar image held by an AvatarStore
+type CachedAvatar struct {
+	// ContentType is the MIME type of the image
+	ContentType string `json:"content_type"`
+	// ETag identifies the image
+	ETag string `json:"etag"`
+	// Data is the encoded image
+	Data []byte `json:"data"`
+	// Expires is when the avatar must be fetched again
+	Expires time.Time `json:"expires"`
+	// NotFound records that the upstream has no avatar for a d=404 request; it holds no image
+	NotFound bool `json:"not_found,omitempty"`
+}
+
+// AvatarStore caches avatar images for the Proxy
+type AvatarStore interface {
+	// Get returns the avatar stored under key or ErrCacheMiss
+	Get(ctx context.Context, key string) (*CachedAvatar, error)
+	// Set stores the avatar under key until it expires
+	Set(ctx context.Context, key string, avatar *CachedAvatar) error
+}
+
+// MemoryStore is an AvatarStore holding avatars in process memory
+type MemoryStore struct {
+	mu         sync.Mutex
+	avatars    map[string]*CachedAvatar
+	maxEntries int
+}
+
+// NewMemoryStore returns a MemoryStore holding at most maxEntries avatars, unlimited if zero; when it
+// is full expired avatars are dropped first, then an arbitrary one
+func NewMemoryStore(maxEntries int) *MemoryStore {
+	return &MemoryStore{avatars: map[string]*CachedAvatar{}, maxEntries: maxEntries}
+}
+
+// Get returns the avatar stored under key or ErrCacheMiss
+func (s *MemoryStore) Get(_ context.Context, key string) (*CachedAvatar, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	avatar, ok := s.avatars[key]
+	if !ok || time.Now().After(avatar.Expires) {
+		delete(s.avatars, key)
+
+		return nil, ErrCacheMiss
+	}
+
+	return avatar, nil
+}
+
+// Set stores the avatar under key until it expires
+func (s *MemoryStore) Set(_ context.Context, key string, avatar *CachedAvatar) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.avatars[key]; !ok && s.maxEntries > 0 && len(s.avatars) >= s.maxEntries {
+		s.evict()
+	}
+
+	s.avatars[key] = avatar
+
+	return nil
+}
+
+// evict removes expired avatars, or a single arbitrary avatar if none expired
+func (s *MemoryStore) evict() {
+	now := time.Now()
+
+	for key, avatar := range s.avatars {
+		if now.After(avatar.Expires) {
+			delete(s.avatars, key)
+		}
+	}
+
+	if len(s.avatars) < s.maxEntries {
+		return
+	}
+
+	for key := range s.avatars {
+		delete(s.avatars, key)
+
+		return
+	}
+}
+
+// DiskStore is an AvatarStore keeping each avatar in a file of a directory
+type DiskStore struct {
+	dir string
+}
+
+// NewDiskStore returns a DiskStore writing to dir, which is created if needed
+func NewDiskStore(dir string) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	return &DiskStore{dir: dir}, nil
+}
+
+// Get returns the avatar stored under key or ErrCacheMiss
+func (s *DiskStore) Get(_ context.Context, key string) (*CachedAvatar, error) {
+	b, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrCacheMiss
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	avatar := &CachedAvatar{}
+	if err := json.Unmarshal(b, avatar); err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(avatar.Expires) {
+		_ = os.Remove(s.path(key))
+
+		return nil, ErrCacheMiss
+	}
+
+	return avatar, nil
+}
+
+// Set stores the avatar under key until it expires; the file is replaced atomically
+func (s *DiskStore) Set(_ context.Context, key string, avatar *CachedAvatar) error {
+	b, err := json.Marshal(avatar)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".avatar-*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path(key))
+}
+
+// path returns the file of a key; keys are hashed so they are always valid file names
+func (s *DiskStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// RedisStore is an AvatarStore backed by redis, such as a client returned by cache.New
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisStore returns a RedisStore storing avatars under keys starting with prefix
+func NewRedisStore(client redis.UniversalClient, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// Get returns the avatar stored under key or ErrCacheMiss
+func (s *RedisStore) Get(ctx context.Context, key string) (*CachedAvatar, error) {
+	b, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrCacheMiss
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	avatar := &CachedAvatar{}
+	if err := json.Unmarshal(b, avatar); err != nil {
+		return nil, err
+	}
+
+	return avatar, nil
+}
+
+// Set stores the avatar under key, letting redis expire it
+func (s *RedisStore) Set(ctx context.Context, key string, avatar *CachedAvatar) error {
+	ttl := time.Until(avatar.Expires)
+	if ttl <= 0 {
+		return nil
+	}
+
+	b, err := json.Marshal(avatar)
+	if err != nil {
+		return err
+	}
+
+	return s.client.Set(ctx, s.prefix+key, b, ttl).Err()
+}
@@ -0,0 +1,240 @@
+package keygen
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/theopenlane/utils/passwd"
+)
+
+// MinPepperLength is the shortest pepper accepted by NewHMACHasher
+const MinPepperLength = 32
+
+// hmacHashAlg identifies HMAC-SHA256 secret hashes
+const hmacHashAlg = "hmac-sha256"
+
+var (
+	// ErrInvalidPepper is returned for an empty pepper ID or a pepper shorter than MinPepperLength
+	ErrInvalidPepper = errors.New("pepper ids must not be empty and peppers must be at least 32 bytes")
+	// ErrUnknownPepper is returned when a hash was created with a pepper the hasher does not hold
+	ErrUnknownPepper = errors.New("secret hash was created with an unknown pepper")
+	// ErrMalformedSecretHash is returned when a stored hash cannot be parsed
+	ErrMalformedSecretHash = errors.New("malformed secret hash")
+	// ErrEmptySecret is returned when hashing or verifying an empty secret
+	ErrEmptySecret = errors.New("secret must not be empty")
+)
+
+// SecretHasher hashes credential secrets for storage and verifies presented secrets against them
+type SecretHasher interface {
+	// Hash returns the storable hash of the secret
+	Hash(secret string) (string, error)
+	// Verify reports whether the secret matches the hash, comparing in constant time
+	Verify(hash, secret string) (bool, error)
+	// NeedsRehash reports whether the hash was created with outdated parameters
+	NeedsRehash(hash string) bool
+}
+
+// Argon2Hasher hashes secrets with argon2id using the parameters of the passwd package; it suits
+// secrets chosen by people but is deliberately slow, so HMACHasher is preferred for generated secrets
+type Argon2Hasher struct{}
+
+// Hash returns the argon2id derived key of the secret
+func (Argon2Hasher) Hash(secret string) (string, error) {
+	if secret == "" {
+		return "", ErrEmptySecret
+	}
+
+	return passwd.CreateDerivedKey(secret)
+}
+
+// Verify reports whether the secret matches the derived key
+func (Argon2Hasher) Verify(hash, secret string) (bool, error) {
+	if secret == "" {
+		return false, ErrEmptySecret
+	}
+
+	return passwd.VerifyDerivedKey(hash, secret)
+}
+
+// NeedsRehash reports whether the derived key was created with other argon2id parameters
+func (Argon2Hasher) NeedsRehash(hash string) bool {
+	return passwd.DerivedKeyNeedsRehash(hash)
+}
+
+// HMACHasher hashes secrets with HMAC-SHA256 keyed by a server side pepper, which is fast enough to
+// verify on every request and safe for long random secrets. Hashes record the ID of their pepper so
+// that peppers can be rotated: hashes of older peppers still verify but need a rehash
+//
+//	$hmac-sha256$k=2024-01$<base64 mac>
+type HMACHasher struct {
+	current string
+	peppers map[string][]byte
+}
+
+// NewHMACHasher returns an HMACHasher hashing with the pepper of the current ID and verifying with any
+// of the peppers
+func NewHMACHasher(current string, peppers map[string][]byte) (*HMACHasher, error) {
+	if _, ok := peppers[current]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownPepper, current)
+	}
+
+	h := &HMACHasher{current: current, peppers: make(map[string][]byte, len(peppers))}
+
+	for id, pepper := range peppers {
+		if id == "" || strings.ContainsRune(id, '$') || len(pepper) < MinPepperLength {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPepper, id)
+		}
+
+		h.peppers[id] = append([]byte(nil), pepper...)
+	}
+
+	return h, nil
+}
+
+// Hash returns the HMAC-SHA256 of the secret under the current pepper
+func (h *HMACHasher) Hash(secret string) (string, error) {
+	if secret == "" {
+		return "", ErrEmptySecret
+	}
+
+	mac := h.mac(h.peppers[h.current], secret)
+
+	return fmt.Sprintf("$%s$k=%s$%s", hmacHashAlg, h.current, base64.RawStdEncoding.EncodeToString(mac)), nil
+}
+
+// Verify reports whether the secret matches the hash, which may use any known pepper
+func (h *HMACHasher) Verify(hash, secret string) (bool, error) {
+	if secret == "" {
+		return false, ErrEmptySecret
+	}
+
+	id, mac, err := parseHMACHash(hash)
+	if err != nil {
+		return false, err
+	}
+
+	pepper, ok := h.peppers[id]
+	if !ok {
+		return false, fmt.Errorf("%w: %q", ErrUnknownPepper, id)
+	}
+
+	return hmac.Equal(mac, h.mac(pepper, secret)), nil
+}
+
+// NeedsRehash reports whether the hash was created with a pepper other than the current one
+func (h *HMACHasher) NeedsRehash(hash string) bool {
+	id, _, err := parseHMACHash(hash)
+
+	return err != nil || id != h.current
+}
+
+// mac returns the HMAC-SHA256 of the secret keyed by the pepper
+func (h *HMACHasher) mac(pepper []byte, secret string) []byte {
+	m := hmac.New(sha256.New, pepper)
+	m.Write([]byte(secret))
+
+	return m.Sum(nil)
+}
+
+// parseHMACHash returns the pepper ID and MAC of an encoded HMAC hash
+func parseHMACHash(hash string) (string, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != "" || parts[1] != hmacHashAlg || !strings.HasPrefix(parts[2], "k=") { //nolint:mnd
+		return "", nil, ErrMalformedSecretHash
+	}
+
+	mac, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(mac) != sha256.Size {
+		return "", nil, ErrMalformedSecretHash
+	}
+
+	return strings.TrimPrefix(parts[2], "k="), mac, nil
+}
+
+// Credential is a newly issued key ID and secret; the secret is handed to the client once and only
+// the hash is stored
+type Credential struct {
+	// KeyID identifies the credential and is stored in the clear
+	KeyID string
+	// Secret is presented by the client and must never be stored
+	Secret string
+	// Hash is the storable hash of the secret
+	Hash string
+}
+
+// CredentialIssuer issues key ID and secret pairs and verifies presented secrets, so that every
+// service creates and checks API credentials the same way
+type CredentialIssuer struct {
+	hasher SecretHasher
+	format *TokenFormat
+}
+
+// IssuerOption configures a CredentialIssuer
+type IssuerOption func(*CredentialIssuer)
+
+// WithSecretHasher sets how secrets are hashed, Argon2Hasher by default
+func WithSecretHasher(hasher SecretHasher) IssuerOption {
+	return func(i *CredentialIssuer) {
+		i.hasher = hasher
+	}
+}
+
+// WithSecretFormat issues secrets as checksummed tokens of the format instead of plain Secret values
+func WithSecretFormat(format *TokenFormat) IssuerOption {
+	return func(i *CredentialIssuer) {
+		i.format = format
+	}
+}
+
+// NewCredentialIssuer returns a CredentialIssuer
+func NewCredentialIssuer(opts ...IssuerOption) *CredentialIssuer {
+	i := &CredentialIssuer{hasher: Argon2Hasher{}}
+
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	return i
+}
+
+// Issue returns a new credential with a random key ID and secret
+func (i *CredentialIssuer) Issue() (*Credential, error) {
+	secret := Secret()
+	if i.format != nil {
+		secret = i.format.Generate()
+	}
+
+	hash, err := i.hasher.Hash(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Credential{KeyID: KeyID(), Secret: secret, Hash: hash}, nil
+}
+
+// Verify reports whether the presented secret matches the stored hash. When the secret matches and the
+// hash was created with outdated parameters a new hash is returned, which should replace the stored one
+func (i *CredentialIssuer) Verify(hash, secret string) (ok bool, rehash string, err error) {
+	if i.format != nil {
+		if err := i.format.Validate(secret); err != nil {
+			return false, "", nil
+		}
+	}
+
+	ok, err = i.hasher.Verify(hash, secret)
+	if err != nil || !ok {
+		return false, "", err
+	}
+
+	if i.hasher.NeedsRehash(hash) {
+		if rehash, err = i.hasher.Hash(secret); err != nil {
+			return true, "", err
+		}
+	}
+
+	return true, rehash, nil
+}
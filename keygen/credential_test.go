@@ -0,0 +1,119 @@
+package keygen_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theopenlane/utils/keygen"
+)
+
+var (
+	pepperA = bytes.Repeat([]byte("a"), keygen.MinPepperLength)
+	pepperB = bytes.Repeat([]byte("b"), keygen.MinPepperLength)
+)
+
+func TestCredentialIssuer(t *testing.T) {
+	hmacHasher, err := keygen.NewHMACHasher("a", map[string][]byte{"a": pepperA})
+	require.NoError(t, err)
+
+	hashers := map[string]keygen.SecretHasher{
+		"argon2": keygen.Argon2Hasher{},
+		"hmac":   hmacHasher,
+	}
+
+	for name, hasher := range hashers {
+		t.Run(name, func(t *testing.T) {
+			issuer := keygen.NewCredentialIssuer(keygen.WithSecretHasher(hasher))
+
+			cred, err := issuer.Issue()
+			require.NoError(t, err)
+			assert.Len(t, cred.KeyID, keygen.KeyIDLength)
+			assert.Len(t, cred.Secret, keygen.SecretLength)
+			assert.NotContains(t, cred.Hash, cred.Secret)
+
+			ok, rehash, err := issuer.Verify(cred.Hash, cred.Secret)
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.Empty(t, rehash)
+
+			ok, _, err = issuer.Verify(cred.Hash, keygen.Secret())
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			_, _, err = issuer.Verify(cred.Hash, "")
+			assert.ErrorIs(t, err, keygen.ErrEmptySecret)
+		})
+	}
+}
+
+func TestCredentialIssuerTokenSecrets(t *testing.T) {
+	format, err := keygen.NewTokenFormat("olt")
+	require.NoError(t, err)
+
+	hasher, err := keygen.NewHMACHasher("a", map[string][]byte{"a": pepperA})
+	require.NoError(t, err)
+
+	issuer := keygen.NewCredentialIssuer(keygen.WithSecretHasher(hasher), keygen.WithSecretFormat(format))
+
+	cred, err := issuer.Issue()
+	require.NoError(t, err)
+	require.NoError(t, format.Validate(cred.Secret))
+
+	ok, _, err := issuer.Verify(cred.Hash, cred.Secret)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, _, err = issuer.Verify(cred.Hash, strings.ToUpper(cred.Secret))
+	require.NoError(t, err)
+	assert.False(t, ok, "malformed tokens are rejected before hashing")
+}
+
+func TestHMACHasherRotation(t *testing.T) {
+	old, err := keygen.NewHMACHasher("a", map[string][]byte{"a": pepperA})
+	require.NoError(t, err)
+
+	hash, err := old.Hash("secret")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$hmac-sha256$k=a$"))
+	assert.False(t, old.NeedsRehash(hash))
+
+	rotated, err := keygen.NewHMACHasher("b", map[string][]byte{"a": pepperA, "b": pepperB})
+	require.NoError(t, err)
+	assert.True(t, rotated.NeedsRehash(hash))
+
+	issuer := keygen.NewCredentialIssuer(keygen.WithSecretHasher(rotated))
+
+	ok, rehash, err := issuer.Verify(hash, "secret")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, strings.HasPrefix(rehash, "$hmac-sha256$k=b$"))
+
+	ok, rehash, err = issuer.Verify(rehash, "secret")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Empty(t, rehash)
+
+	current, err := keygen.NewHMACHasher("b", map[string][]byte{"b": pepperB})
+	require.NoError(t, err)
+
+	_, err = current.Verify(hash, "secret")
+	assert.ErrorIs(t, err, keygen.ErrUnknownPepper)
+
+	_, err = current.Verify("$hmac-sha256$k=b$notbase64!", "secret")
+	assert.ErrorIs(t, err, keygen.ErrMalformedSecretHash)
+}
+
+func TestNewHMACHasher(t *testing.T) {
+	_, err := keygen.NewHMACHasher("a", map[string][]byte{"b": pepperB})
+	assert.ErrorIs(t, err, keygen.ErrUnknownPepper)
+
+	_, err = keygen.NewHMACHasher("a", map[string][]byte{"a": []byte("short")})
+	assert.ErrorIs(t, err, keygen.ErrInvalidPepper)
+
+	_, err = keygen.NewHMACHasher("a$b", map[string][]byte{"a$b": pepperA})
+	assert.ErrorIs(t, err, keygen.ErrInvalidPepper)
+}
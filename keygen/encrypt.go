@@ -10,6 +10,12 @@ import (
 
 // Encrypt encrypts data with key (must be valid 32 char aes key).
 func Encrypt(data []byte, key string) (string, error) {
+	return EncryptWithAD(data, key, nil)
+}
+
+// EncryptWithAD encrypts data with key like Encrypt and authenticates the associated data, such as a
+// record ID, which is not stored in the result and must be passed to DecryptWithAD unchanged
+func EncryptWithAD(data []byte, key string, ad []byte) (string, error) {
 	block, err := aes.NewCipher([]byte(key))
 	if err != nil {
 		return "", err
@@ -27,7 +33,7 @@ func Encrypt(data []byte, key string) (string, error) {
 		return "", err
 	}
 
-	cipherByte := gcm.Seal(nonce, nonce, data, nil)
+	cipherByte := gcm.Seal(nonce, nonce, data, ad)
 
 	result := base64.StdEncoding.EncodeToString(cipherByte)
 
@@ -36,6 +42,11 @@ func Encrypt(data []byte, key string) (string, error) {
 
 // Decrypt decrypts encrypted text with key (must be valid 32 chars aes key).
 func Decrypt(cipherText string, key string) ([]byte, error) {
+	return DecryptWithAD(cipherText, key, nil)
+}
+
+// DecryptWithAD decrypts encrypted text created by EncryptWithAD with the same associated data
+func DecryptWithAD(cipherText string, key string, ad []byte) ([]byte, error) {
 	block, err := aes.NewCipher([]byte(key))
 	if err != nil {
 		return nil, err
@@ -53,7 +64,11 @@ func Decrypt(cipherText string, key string) ([]byte, error) {
 		return nil, err
 	}
 
+	if len(cipherByte) < nonceSize {
+		return nil, ErrMalformedCiphertext
+	}
+
 	nonce, cipherByteClean := cipherByte[:nonceSize], cipherByte[nonceSize:]
 
-	return gcm.Open(nil, nonce, cipherByteClean, nil)
+	return gcm.Open(nil, nonce, cipherByteClean, ad)
 }
@@ -0,0 +1,256 @@
+package keygen
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	crand "crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Sizes of the envelope ciphertext format
+const (
+	// KeySize is the size of key-encryption keys and data keys, which are AES-256 keys
+	KeySize = 32
+
+	// envelopeVersion is the version byte of ciphertexts created by a Keyring
+	envelopeVersion byte = 1
+	// envelopeHeaderSize is the size of the version byte and key ID
+	envelopeHeaderSize = 5
+	// gcmNonceSize and gcmTagSize are the standard AES-GCM nonce and tag sizes
+	gcmNonceSize = 12
+	gcmTagSize   = 16
+	// wrappedKeySize is the size of a data key sealed by a key-encryption key
+	wrappedKeySize = gcmNonceSize + KeySize + gcmTagSize
+	// envelopeOverhead is the size of a ciphertext of an empty plaintext
+	envelopeOverhead = envelopeHeaderSize + wrappedKeySize + gcmNonceSize + gcmTagSize
+)
+
+var (
+	// ErrInvalidKeySize is returned for a key-encryption key that is not KeySize bytes
+	ErrInvalidKeySize = errors.New("encryption keys must be 32 bytes")
+	// ErrUnknownKeyID is returned when a ciphertext was encrypted under a key the keyring does not hold
+	ErrUnknownKeyID = errors.New("unknown encryption key id")
+	// ErrUnsupportedCiphertextVersion is returned for a ciphertext with an unknown version byte
+	ErrUnsupportedCiphertextVersion = errors.New("unsupported ciphertext version")
+	// ErrMalformedCiphertext is returned for a ciphertext that is too short to be valid
+	ErrMalformedCiphertext = errors.New("malformed ciphertext")
+	// ErrDecryptionFailed is returned when a ciphertext or its data key fails authentication
+	ErrDecryptionFailed = errors.New("decryption failed")
+)
+
+// Keyring encrypts data with envelope encryption: every ciphertext has its own random data key,
+// which is wrapped by a key-encryption key (KEK) of the keyring and stored with the ciphertext
+//
+//	version (1) | kek id (4) | wrapped data key (60) | nonce (12) | ciphertext | tag (16)
+//
+// New ciphertexts use the primary KEK; older KEKs are kept to decrypt existing ciphertexts, which
+// Rewrap moves to the primary KEK by re-wrapping only their data key
+type Keyring struct {
+	primary uint32
+	keks    map[uint32]cipher.AEAD
+}
+
+// NewKeyring returns a Keyring encrypting under the KEK with the primary ID and decrypting with any
+// of the keys, which must all be KeySize bytes
+func NewKeyring(primary uint32, keys map[uint32][]byte) (*Keyring, error) {
+	if _, ok := keys[primary]; !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownKeyID, primary)
+	}
+
+	k := &Keyring{primary: primary, keks: make(map[uint32]cipher.AEAD, len(keys))}
+
+	for id, key := range keys {
+		aead, err := newGCM(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w", id, err)
+		}
+
+		k.keks[id] = aead
+	}
+
+	return k, nil
+}
+
+// GenerateKey returns a new random KeySize key
+func GenerateKey() []byte {
+	return GenerateRandomBytes(KeySize)
+}
+
+// PrimaryKeyID returns the ID of the KEK new ciphertexts are encrypted under
+func (k *Keyring) PrimaryKeyID() uint32 {
+	return k.primary
+}
+
+// Encrypt encrypts the plaintext under a new data key wrapped by the primary KEK
+func (k *Keyring) Encrypt(plaintext []byte) ([]byte, error) {
+	return k.EncryptWithAD(plaintext, nil)
+}
+
+// EncryptWithAD encrypts the plaintext like Encrypt and authenticates the associated data, such as a
+// record ID, so that the ciphertext cannot be copied to another record
+func (k *Keyring) EncryptWithAD(plaintext, ad []byte) ([]byte, error) {
+	dek := GenerateKey()
+
+	data, err := newGCM(dek)
+	if err != nil {
+		return nil, err
+	}
+
+	out := k.appendHeader(make([]byte, 0, envelopeOverhead+len(plaintext)), k.primary)
+
+	if out, err = k.wrap(out, dek); err != nil {
+		return nil, err
+	}
+
+	nonce, err := randomNonce(gcmNonceSize)
+	if err != nil {
+		return nil, err
+	}
+
+	out = append(out, nonce...)
+
+	return data.Seal(out, nonce, plaintext, ad), nil
+}
+
+// Decrypt decrypts a ciphertext created by Encrypt with the KEK named in its header
+func (k *Keyring) Decrypt(ciphertext []byte) ([]byte, error) {
+	return k.DecryptWithAD(ciphertext, nil)
+}
+
+// DecryptWithAD decrypts a ciphertext created by EncryptWithAD with the same associated data
+func (k *Keyring) DecryptWithAD(ciphertext, ad []byte) ([]byte, error) {
+	dek, body, err := k.unwrap(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := newGCM(dek)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := data.Open(nil, body[:gcmNonceSize], body[gcmNonceSize:], ad)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+
+	return plaintext, nil
+}
+
+// Rewrap re-wraps the data key of a ciphertext under the primary KEK, leaving the encrypted data
+// and any associated data untouched; ciphertexts already under the primary KEK are returned unchanged
+func (k *Keyring) Rewrap(ciphertext []byte) ([]byte, error) {
+	id, err := CiphertextKeyID(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	if id == k.primary {
+		return ciphertext, nil
+	}
+
+	dek, body, err := k.unwrap(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	out := k.appendHeader(make([]byte, 0, len(ciphertext)), k.primary)
+
+	if out, err = k.wrap(out, dek); err != nil {
+		return nil, err
+	}
+
+	return append(out, body...), nil
+}
+
+// NeedsRewrap reports whether the ciphertext is encrypted under a KEK other than the primary one
+func (k *Keyring) NeedsRewrap(ciphertext []byte) bool {
+	id, err := CiphertextKeyID(ciphertext)
+
+	return err == nil && id != k.primary
+}
+
+// CiphertextKeyID returns the ID of the KEK a Keyring ciphertext is encrypted under
+func CiphertextKeyID(ciphertext []byte) (uint32, error) {
+	if len(ciphertext) < envelopeOverhead {
+		return 0, ErrMalformedCiphertext
+	}
+
+	if ciphertext[0] != envelopeVersion {
+		return 0, fmt.Errorf("%w: %d", ErrUnsupportedCiphertextVersion, ciphertext[0])
+	}
+
+	return binary.BigEndian.Uint32(ciphertext[1:envelopeHeaderSize]), nil
+}
+
+// appendHeader appends the version byte and KEK ID to out
+func (k *Keyring) appendHeader(out []byte, id uint32) []byte {
+	out = append(out, envelopeVersion)
+
+	return binary.BigEndian.AppendUint32(out, id)
+}
+
+// wrap appends the data key sealed by the KEK named in the header at the start of out, which is
+// authenticated with it so that the wrapped key cannot be moved to another header
+func (k *Keyring) wrap(out, dek []byte) ([]byte, error) {
+	header := out[:envelopeHeaderSize]
+
+	nonce, err := randomNonce(gcmNonceSize)
+	if err != nil {
+		return nil, err
+	}
+
+	out = append(out, nonce...)
+
+	return k.keks[binary.BigEndian.Uint32(header[1:])].Seal(out, nonce, dek, header), nil
+}
+
+// unwrap returns the data key of a ciphertext and the nonce and encrypted data following it
+func (k *Keyring) unwrap(ciphertext []byte) (dek, body []byte, err error) {
+	id, err := CiphertextKeyID(ciphertext)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kek, ok := k.keks[id]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %d", ErrUnknownKeyID, id)
+	}
+
+	header := ciphertext[:envelopeHeaderSize]
+	wrapped := ciphertext[envelopeHeaderSize : envelopeHeaderSize+wrappedKeySize]
+
+	dek, err = kek.Open(nil, wrapped[:gcmNonceSize], wrapped[gcmNonceSize:], header)
+	if err != nil {
+		return nil, nil, ErrDecryptionFailed
+	}
+
+	return dek, ciphertext[envelopeHeaderSize+wrappedKeySize:], nil
+}
+
+// newGCM returns AES-256-GCM with the key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, ErrInvalidKeySize
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// randomNonce returns a random nonce of the given size
+func randomNonce(size int) ([]byte, error) {
+	nonce := make([]byte, size)
+	if _, err := io.ReadFull(crand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return nonce, nil
+}
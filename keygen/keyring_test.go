@@ -0,0 +1,119 @@
+package keygen_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theopenlane/utils/keygen"
+)
+
+func TestKeyring(t *testing.T) {
+	keys := map[uint32][]byte{1: keygen.GenerateKey()}
+
+	ring, err := keygen.NewKeyring(1, keys)
+	require.NoError(t, err)
+
+	for _, plaintext := range []string{"", "sfunk@theopenlane.io"} {
+		ciphertext, err := ring.Encrypt([]byte(plaintext))
+		require.NoError(t, err)
+		assert.Len(t, ciphertext, 93+len(plaintext))
+
+		id, err := keygen.CiphertextKeyID(ciphertext)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(1), id)
+
+		decrypted, err := ring.Decrypt(ciphertext)
+		require.NoError(t, err)
+		assert.Equal(t, plaintext, string(decrypted))
+	}
+
+	a, err := ring.Encrypt([]byte("same"))
+	require.NoError(t, err)
+
+	b, err := ring.Encrypt([]byte("same"))
+	require.NoError(t, err)
+	assert.NotEqual(t, a, b)
+}
+
+func TestKeyringRotation(t *testing.T) {
+	keys := map[uint32][]byte{1: keygen.GenerateKey()}
+
+	old, err := keygen.NewKeyring(1, keys)
+	require.NoError(t, err)
+
+	ciphertext, err := old.Encrypt([]byte("secret"))
+	require.NoError(t, err)
+	assert.False(t, old.NeedsRewrap(ciphertext))
+
+	keys[2] = keygen.GenerateKey()
+
+	rotated, err := keygen.NewKeyring(2, keys)
+	require.NoError(t, err)
+	assert.True(t, rotated.NeedsRewrap(ciphertext))
+
+	decrypted, err := rotated.Decrypt(ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(decrypted))
+
+	rewrapped, err := rotated.Rewrap(ciphertext)
+	require.NoError(t, err)
+	assert.False(t, rotated.NeedsRewrap(rewrapped))
+	assert.Len(t, rewrapped, len(ciphertext))
+	assert.Equal(t, ciphertext[len(ciphertext)-32:], rewrapped[len(rewrapped)-32:], "the encrypted data is unchanged")
+
+	id, err := keygen.CiphertextKeyID(rewrapped)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2), id)
+
+	again, err := rotated.Rewrap(rewrapped)
+	require.NoError(t, err)
+	assert.Equal(t, rewrapped, again)
+
+	// once the old KEK is retired only rewrapped ciphertexts can be decrypted
+	current, err := keygen.NewKeyring(2, map[uint32][]byte{2: keys[2]})
+	require.NoError(t, err)
+
+	decrypted, err = current.Decrypt(rewrapped)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(decrypted))
+
+	_, err = current.Decrypt(ciphertext)
+	assert.ErrorIs(t, err, keygen.ErrUnknownKeyID)
+}
+
+func TestKeyringErrors(t *testing.T) {
+	_, err := keygen.NewKeyring(1, map[uint32][]byte{2: keygen.GenerateKey()})
+	assert.ErrorIs(t, err, keygen.ErrUnknownKeyID)
+
+	_, err = keygen.NewKeyring(1, map[uint32][]byte{1: []byte("abcdabcdabcdabcd")})
+	assert.ErrorIs(t, err, keygen.ErrInvalidKeySize)
+
+	ring, err := keygen.NewKeyring(1, map[uint32][]byte{1: keygen.GenerateKey()})
+	require.NoError(t, err)
+
+	ciphertext, err := ring.Encrypt([]byte("secret"))
+	require.NoError(t, err)
+
+	_, err = ring.Decrypt(ciphertext[:20])
+	assert.ErrorIs(t, err, keygen.ErrMalformedCiphertext)
+
+	versioned := append([]byte{9}, ciphertext[1:]...)
+	_, err = ring.Decrypt(versioned)
+	assert.ErrorIs(t, err, keygen.ErrUnsupportedCiphertextVersion)
+
+	for _, i := range []int{3, 10, len(ciphertext) - 1} {
+		tampered := append([]byte(nil), ciphertext...)
+		tampered[i] ^= 1
+
+		_, err = ring.Decrypt(tampered)
+		assert.Error(t, err, i)
+	}
+
+	other, err := keygen.NewKeyring(1, map[uint32][]byte{1: keygen.GenerateKey()})
+	require.NoError(t, err)
+
+	_, err = other.Decrypt(ciphertext)
+	assert.ErrorIs(t, err, keygen.ErrDecryptionFailed)
+}
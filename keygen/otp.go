@@ -0,0 +1,352 @@
+package keygen
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OTPAlgorithm selects the HMAC of one-time passwords
+type OTPAlgorithm string
+
+const (
+	// OTPSHA1 is the algorithm of RFC 4226, the default and the only one supported by every authenticator app
+	OTPSHA1 OTPAlgorithm = "SHA1"
+	// OTPSHA256 uses HMAC-SHA256
+	OTPSHA256 OTPAlgorithm = "SHA256"
+	// OTPSHA512 uses HMAC-SHA512
+	OTPSHA512 OTPAlgorithm = "SHA512"
+)
+
+// Defaults for one-time passwords
+const (
+	// DefaultOTPDigits is the number of digits of a code
+	DefaultOTPDigits = 6
+	// DefaultOTPPeriod is how long a TOTP code is valid
+	DefaultOTPPeriod = 30 * time.Second
+	// DefaultOTPSkew is the number of periods before and after the current one a TOTP code is accepted in
+	DefaultOTPSkew = 1
+	// OTPSecretSize is the size of secrets created by GenerateOTPSecret, 160 bits as RFC 4226 recommends
+	OTPSecretSize = 20
+
+	// minOTPSecretSize is the 128 bit minimum secret size of RFC 4226
+	minOTPSecretSize = 16
+)
+
+var (
+	// ErrInvalidOTPSecret is returned for a secret that is not base32 or shorter than 128 bits
+	ErrInvalidOTPSecret = errors.New("otp secrets must be base32 encoded and at least 128 bits")
+	// ErrInvalidOTPDigits is returned for a number of digits other than 6, 7 or 8
+	ErrInvalidOTPDigits = errors.New("otp codes must have 6 to 8 digits")
+	// ErrInvalidOTPPeriod is returned for a TOTP period that is not a positive number of seconds
+	ErrInvalidOTPPeriod = errors.New("otp period must be a positive number of seconds")
+	// ErrInvalidOTPAlgorithm is returned for an algorithm other than SHA1, SHA256 or SHA512
+	ErrInvalidOTPAlgorithm = errors.New("otp algorithm must be SHA1, SHA256 or SHA512")
+	// ErrOTPReplayed is returned when a TOTP code, or an earlier one, was already used
+	ErrOTPReplayed = errors.New("otp code was already used")
+)
+
+// otpEncoding is the unpadded base32 encoding of secrets used by authenticator apps
+var otpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// OTPStore records the last TOTP time step used by each account, so that a code cannot be used twice
+type OTPStore interface {
+	// Use records the counter for the key, returning ErrOTPReplayed if it is not greater than the last
+	// counter recorded; the check and the update must be atomic
+	Use(ctx context.Context, key string, counter uint64) error
+}
+
+// MemoryOTPStore is an OTPStore in process memory; services with several instances need a shared store
+type MemoryOTPStore struct {
+	mu   sync.Mutex
+	used map[string]uint64
+}
+
+// NewMemoryOTPStore returns a MemoryOTPStore
+func NewMemoryOTPStore() *MemoryOTPStore {
+	return &MemoryOTPStore{used: map[string]uint64{}}
+}
+
+// Use records the counter for the key unless it is not greater than the last one recorded
+func (s *MemoryOTPStore) Use(_ context.Context, key string, counter uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if last, ok := s.used[key]; ok && counter <= last {
+		return ErrOTPReplayed
+	}
+
+	s.used[key] = counter
+
+	return nil
+}
+
+// OTP generates and verifies HOTP (RFC 4226) and TOTP (RFC 6238) one-time passwords
+type OTP struct {
+	digits int
+	period time.Duration
+	alg    OTPAlgorithm
+	skew   uint
+	issuer string
+	store  OTPStore
+	now    func() time.Time
+}
+
+// OTPOption configures an OTP
+type OTPOption func(*OTP)
+
+// WithOTPDigits sets the number of digits of a code, DefaultOTPDigits by default
+func WithOTPDigits(digits int) OTPOption {
+	return func(o *OTP) {
+		o.digits = digits
+	}
+}
+
+// WithOTPPeriod sets how long a TOTP code is valid, DefaultOTPPeriod by default
+func WithOTPPeriod(period time.Duration) OTPOption {
+	return func(o *OTP) {
+		o.period = period
+	}
+}
+
+// WithOTPAlgorithm sets the HMAC algorithm, OTPSHA1 by default
+func WithOTPAlgorithm(alg OTPAlgorithm) OTPOption {
+	return func(o *OTP) {
+		o.alg = alg
+	}
+}
+
+// WithOTPSkew sets how many TOTP periods, or HOTP counters, after the expected one a code is accepted
+// in; TOTP codes are also accepted for as many periods before. DefaultOTPSkew by default
+func WithOTPSkew(skew uint) OTPOption {
+	return func(o *OTP) {
+		o.skew = skew
+	}
+}
+
+// WithOTPIssuer sets the issuer shown by authenticator apps for provisioning URIs
+func WithOTPIssuer(issuer string) OTPOption {
+	return func(o *OTP) {
+		o.issuer = issuer
+	}
+}
+
+// WithOTPStore sets where used TOTP codes are recorded, a MemoryOTPStore by default
+func WithOTPStore(store OTPStore) OTPOption {
+	return func(o *OTP) {
+		o.store = store
+	}
+}
+
+// WithOTPClock sets the function returning the current time, time.Now by default
+func WithOTPClock(now func() time.Time) OTPOption {
+	return func(o *OTP) {
+		o.now = now
+	}
+}
+
+// NewOTP returns an OTP
+func NewOTP(opts ...OTPOption) (*OTP, error) {
+	o := &OTP{
+		digits: DefaultOTPDigits,
+		period: DefaultOTPPeriod,
+		alg:    OTPSHA1,
+		skew:   DefaultOTPSkew,
+		store:  NewMemoryOTPStore(),
+		now:    time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.digits < 6 || o.digits > 8 { //nolint:mnd
+		return nil, fmt.Errorf("%w: %d", ErrInvalidOTPDigits, o.digits)
+	}
+
+	if o.period < time.Second || o.period%time.Second != 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidOTPPeriod, o.period)
+	}
+
+	if o.hash() == nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidOTPAlgorithm, o.alg)
+	}
+
+	return o, nil
+}
+
+// GenerateOTPSecret returns a new random base32 encoded secret
+func GenerateOTPSecret() string {
+	return otpEncoding.EncodeToString(GenerateRandomBytes(OTPSecretSize))
+}
+
+// HOTP returns the code of the base32 secret for the counter
+func (o *OTP) HOTP(secret string, counter uint64) (string, error) {
+	key, err := decodeOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+
+	return o.code(key, counter), nil
+}
+
+// VerifyHOTP checks a code against the counter and the skew counters after it. On success it returns
+// the counter to store for the next verification, one past the matching counter
+func (o *OTP) VerifyHOTP(secret, code string, counter uint64) (next uint64, ok bool, err error) {
+	key, err := decodeOTPSecret(secret)
+	if err != nil {
+		return counter, false, err
+	}
+
+	for i := uint64(0); i <= uint64(o.skew); i++ {
+		if Equal(code, o.code(key, counter+i)) {
+			return counter + i + 1, true, nil
+		}
+	}
+
+	return counter, false, nil
+}
+
+// TOTP returns the current code of the base32 secret
+func (o *OTP) TOTP(secret string) (string, error) {
+	return o.TOTPAt(secret, o.now())
+}
+
+// TOTPAt returns the code of the base32 secret at the time
+func (o *OTP) TOTPAt(secret string, t time.Time) (string, error) {
+	return o.HOTP(secret, o.timeStep(t))
+}
+
+// VerifyTOTP checks a code against the current period and the skew periods around it. The matching time
+// step is recorded in the store under key, usually the account ID, and ErrOTPReplayed is returned when
+// the code or a later one was already used
+func (o *OTP) VerifyTOTP(ctx context.Context, key, secret, code string) (bool, error) {
+	secretKey, err := decodeOTPSecret(secret)
+	if err != nil {
+		return false, err
+	}
+
+	step := o.timeStep(o.now())
+
+	for i := -int64(o.skew); i <= int64(o.skew); i++ {
+		counter := step + uint64(i) //nolint:gosec
+		if (i < 0 && counter > step) || !Equal(code, o.code(secretKey, counter)) {
+			continue
+		}
+
+		if err := o.store.Use(ctx, key, counter); err != nil {
+			return false, err
+		}
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// TOTPURI returns the otpauth:// URI authenticator apps are provisioned with, usually shown as a QR code
+func (o *OTP) TOTPURI(secret, account string) string {
+	params := o.uriParams(secret)
+	params.Set("period", strconv.Itoa(int(o.period/time.Second)))
+
+	return o.uri("totp", account, params)
+}
+
+// HOTPURI returns the otpauth:// URI provisioning an HOTP secret starting at the counter
+func (o *OTP) HOTPURI(secret, account string, counter uint64) string {
+	params := o.uriParams(secret)
+	params.Set("counter", strconv.FormatUint(counter, 10))
+
+	return o.uri("hotp", account, params)
+}
+
+// uriParams returns the provisioning URI parameters common to TOTP and HOTP
+func (o *OTP) uriParams(secret string) url.Values {
+	params := url.Values{}
+	params.Set("secret", normalizeOTPSecret(secret))
+	params.Set("algorithm", string(o.alg))
+	params.Set("digits", strconv.Itoa(o.digits))
+
+	if o.issuer != "" {
+		params.Set("issuer", o.issuer)
+	}
+
+	return params
+}
+
+// uri returns a provisioning URI labelled with the issuer and account
+func (o *OTP) uri(kind, account string, params url.Values) string {
+	label := account
+	if o.issuer != "" {
+		label = o.issuer + ":" + account
+	}
+
+	u := url.URL{Scheme: "otpauth", Host: kind, Path: "/" + label, RawQuery: params.Encode()}
+
+	return u.String()
+}
+
+// timeStep returns the TOTP counter of the time
+func (o *OTP) timeStep(t time.Time) uint64 {
+	return uint64(t.Unix()) / uint64(o.period/time.Second) //nolint:gosec
+}
+
+// code returns the HOTP value of the key and counter: the dynamically truncated HMAC of the counter
+// modulo 10^digits, zero padded
+func (o *OTP) code(key []byte, counter uint64) string {
+	mac := hmac.New(o.hash(), key)
+	_ = binary.Write(mac, binary.BigEndian, counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f                                    //nolint:mnd
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff //nolint:mnd
+
+	mod := uint32(1)
+	for range o.digits {
+		mod *= 10 //nolint:mnd
+	}
+
+	return fmt.Sprintf("%0*d", o.digits, value%mod)
+}
+
+// hash returns the hash function of the algorithm, nil if it is unknown
+func (o *OTP) hash() func() hash.Hash {
+	switch o.alg {
+	case OTPSHA1:
+		return sha1.New
+	case OTPSHA256:
+		return sha256.New
+	case OTPSHA512:
+		return sha512.New
+	default:
+		return nil
+	}
+}
+
+// normalizeOTPSecret uppercases a base32 secret and removes spaces and padding, as secrets are often
+// shown in groups to be typed in
+func normalizeOTPSecret(secret string) string {
+	return strings.TrimRight(strings.ToUpper(strings.ReplaceAll(secret, " ", "")), "=")
+}
+
+// decodeOTPSecret decodes a base32 secret
+func decodeOTPSecret(secret string) ([]byte, error) {
+	key, err := otpEncoding.DecodeString(normalizeOTPSecret(secret))
+	if err != nil || len(key) < minOTPSecretSize {
+		return nil, ErrInvalidOTPSecret
+	}
+
+	return key, nil
+}
@@ -0,0 +1,186 @@
+package keygen_test
+
+import (
+	"context"
+	"encoding/base32"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theopenlane/utils/keygen"
+)
+
+// secrets of the RFC 4226 and RFC 6238 test vectors
+var (
+	rfcSHA1Secret   = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	rfcSHA256Secret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890123456789012"))
+	rfcSHA512Secret = base32.StdEncoding.EncodeToString([]byte("1234567890123456789012345678901234567890123456789012345678901234"))
+)
+
+func TestHOTP(t *testing.T) {
+	otp, err := keygen.NewOTP()
+	require.NoError(t, err)
+
+	// RFC 4226 appendix D
+	expected := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+
+	for counter, code := range expected {
+		got, err := otp.HOTP(rfcSHA1Secret, uint64(counter)) //nolint:gosec
+		require.NoError(t, err)
+		assert.Equal(t, code, got, counter)
+	}
+
+	next, ok, err := otp.VerifyHOTP(rfcSHA1Secret, "359152", 1)
+	require.NoError(t, err)
+	assert.True(t, ok, "codes within the skew window are accepted")
+	assert.Equal(t, uint64(3), next)
+
+	next, ok, err = otp.VerifyHOTP(rfcSHA1Secret, "969429", 1)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, uint64(1), next)
+
+	_, ok, err = otp.VerifyHOTP(rfcSHA1Secret, "755224", 1)
+	require.NoError(t, err)
+	assert.False(t, ok, "earlier counters are rejected")
+}
+
+func TestTOTPVectors(t *testing.T) {
+	// RFC 6238 appendix B
+	tests := []struct {
+		time   int64
+		sha1   string
+		sha256 string
+		sha512 string
+	}{
+		{59, "94287082", "46119246", "90693936"},
+		{1111111109, "07081804", "68084774", "25091201"},
+		{1111111111, "14050471", "67062674", "99943326"},
+		{1234567890, "89005924", "91819424", "93441116"},
+		{2000000000, "69279037", "90698825", "38618901"},
+		{20000000000, "65353130", "77737706", "47863826"},
+	}
+
+	algorithms := []struct {
+		alg    keygen.OTPAlgorithm
+		secret string
+		code   func(i int) string
+	}{
+		{keygen.OTPSHA1, rfcSHA1Secret, func(i int) string { return tests[i].sha1 }},
+		{keygen.OTPSHA256, rfcSHA256Secret, func(i int) string { return tests[i].sha256 }},
+		{keygen.OTPSHA512, rfcSHA512Secret, func(i int) string { return tests[i].sha512 }},
+	}
+
+	for _, a := range algorithms {
+		otp, err := keygen.NewOTP(keygen.WithOTPDigits(8), keygen.WithOTPAlgorithm(a.alg))
+		require.NoError(t, err)
+
+		for i, tc := range tests {
+			t.Run(fmt.Sprintf("%s-%d", a.alg, tc.time), func(t *testing.T) {
+				code, err := otp.TOTPAt(a.secret, time.Unix(tc.time, 0))
+				require.NoError(t, err)
+				assert.Equal(t, a.code(i), code)
+			})
+		}
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	secret := keygen.GenerateOTPSecret()
+	ctx := context.Background()
+
+	otp, err := keygen.NewOTP(keygen.WithOTPClock(func() time.Time { return now }))
+	require.NoError(t, err)
+
+	code, err := otp.TOTP(secret)
+	require.NoError(t, err)
+	assert.Len(t, code, keygen.DefaultOTPDigits)
+
+	previous, err := otp.TOTPAt(secret, now.Add(-keygen.DefaultOTPPeriod))
+	require.NoError(t, err)
+
+	stale, err := otp.TOTPAt(secret, now.Add(-2*keygen.DefaultOTPPeriod))
+	require.NoError(t, err)
+
+	ok, err := otp.VerifyTOTP(ctx, "user-1", secret, stale)
+	require.NoError(t, err)
+	assert.False(t, ok, "codes outside the skew window are rejected")
+
+	ok, err = otp.VerifyTOTP(ctx, "user-1", secret, code)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = otp.VerifyTOTP(ctx, "user-1", secret, code)
+	assert.ErrorIs(t, err, keygen.ErrOTPReplayed)
+
+	_, err = otp.VerifyTOTP(ctx, "user-1", secret, previous)
+	assert.ErrorIs(t, err, keygen.ErrOTPReplayed, "codes older than the last used one are rejected")
+
+	ok, err = otp.VerifyTOTP(ctx, "user-2", secret, previous)
+	require.NoError(t, err)
+	assert.True(t, ok, "codes within the skew window are accepted")
+
+	now = now.Add(keygen.DefaultOTPPeriod)
+
+	next, err := otp.TOTP(secret)
+	require.NoError(t, err)
+
+	ok, err = otp.VerifyTOTP(ctx, "user-1", secret, next)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = otp.VerifyTOTP(ctx, "user-1", "not base32!", next)
+	assert.ErrorIs(t, err, keygen.ErrInvalidOTPSecret)
+}
+
+func TestOTPURI(t *testing.T) {
+	otp, err := keygen.NewOTP(keygen.WithOTPIssuer("Openlane"))
+	require.NoError(t, err)
+
+	u, err := url.Parse(otp.TOTPURI("jbsw y3dp ehpk 3pxp jbsw y3dp ehpk 3pxp", "sfunk@theopenlane.io"))
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/Openlane:sfunk@theopenlane.io", u.Path)
+	assert.Equal(t, url.Values{
+		"secret":    {"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"},
+		"issuer":    {"Openlane"},
+		"algorithm": {"SHA1"},
+		"digits":    {"6"},
+		"period":    {"30"},
+	}, u.Query())
+
+	u, err = url.Parse(otp.HOTPURI(rfcSHA1Secret, "sfunk@theopenlane.io", 5))
+	require.NoError(t, err)
+	assert.Equal(t, "hotp", u.Host)
+	assert.Equal(t, "5", u.Query().Get("counter"))
+}
+
+func TestNewOTP(t *testing.T) {
+	tests := []struct {
+		opt keygen.OTPOption
+		err error
+	}{
+		{keygen.WithOTPDigits(4), keygen.ErrInvalidOTPDigits},
+		{keygen.WithOTPDigits(10), keygen.ErrInvalidOTPDigits},
+		{keygen.WithOTPPeriod(0), keygen.ErrInvalidOTPPeriod},
+		{keygen.WithOTPPeriod(1500 * time.Millisecond), keygen.ErrInvalidOTPPeriod},
+		{keygen.WithOTPAlgorithm("MD5"), keygen.ErrInvalidOTPAlgorithm},
+	}
+
+	for _, tc := range tests {
+		_, err := keygen.NewOTP(tc.opt)
+		assert.ErrorIs(t, err, tc.err)
+	}
+
+	otp, err := keygen.NewOTP()
+	require.NoError(t, err)
+
+	_, err = otp.HOTP(base32.StdEncoding.EncodeToString([]byte("too short")), 0)
+	assert.ErrorIs(t, err, keygen.ErrInvalidOTPSecret)
+}
@@ -0,0 +1,230 @@
+package keygen
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/theopenlane/utils/rout"
+	"github.com/theopenlane/utils/ulids"
+)
+
+// SigningAlgorithm selects the HMAC used to sign tokens
+type SigningAlgorithm string
+
+const (
+	// SigningHS256 signs tokens with HS256 and is the default
+	SigningHS256 SigningAlgorithm = "HS256"
+	// SigningHS512 signs tokens with HS512
+	SigningHS512 SigningAlgorithm = "HS512"
+)
+
+// MinSigningKeyLength is the shortest signing key accepted by NewTokenSigner
+const MinSigningKeyLength = 32
+
+var (
+	// ErrInvalidSigningKey is returned for an empty key ID or a key shorter than MinSigningKeyLength
+	ErrInvalidSigningKey = errors.New("signing key ids must not be empty and keys must be at least 32 bytes")
+	// ErrInvalidSigningAlgorithm is returned for a signing algorithm other than HS256 or HS512
+	ErrInvalidSigningAlgorithm = errors.New("signing algorithm must be HS256 or HS512")
+
+	// ErrSignedTokenMalformed is reported when a token cannot be decoded
+	ErrSignedTokenMalformed = errors.New("malformed signed token")
+	// ErrSignedTokenSignature is reported when the signature of a token does not match
+	ErrSignedTokenSignature = errors.New("signed token has an invalid signature")
+	// ErrUnknownSigningKey is reported when a token was signed with a key the signer does not hold
+	ErrUnknownSigningKey = errors.New("signed token was signed with an unknown key")
+	// ErrSignedTokenExpired is reported when a token is past its expiry
+	ErrSignedTokenExpired = errors.New("signed token has expired")
+	// ErrSignedTokenPurpose is reported when a token was issued for another purpose
+	ErrSignedTokenPurpose = errors.New("signed token was issued for another purpose")
+)
+
+// SignedTokenError is returned when a signed token fails verification. It matches both its reason,
+// such as ErrSignedTokenExpired, and the rout error an API should respond with, so handlers can check
+// errors.Is(err, rout.ErrExpiredCredentials) without knowing about signed tokens
+type SignedTokenError struct {
+	// Reason is the keygen error explaining why verification failed
+	Reason error
+}
+
+// Error returns the SignedTokenError in string format
+func (e *SignedTokenError) Error() string {
+	return e.Reason.Error()
+}
+
+// Unwrap returns the reason and its rout error
+func (e *SignedTokenError) Unwrap() []error {
+	return []error{e.Reason, e.Credentials()}
+}
+
+// Credentials returns the rout error for the reason: rout.ErrExpiredCredentials for expired tokens and
+// rout.ErrInvalidCredentials otherwise
+func (e *SignedTokenError) Credentials() error {
+	if errors.Is(e.Reason, ErrSignedTokenExpired) {
+		return rout.ErrExpiredCredentials
+	}
+
+	return rout.ErrInvalidCredentials
+}
+
+// newSignedTokenError returns a SignedTokenError for the reason
+func newSignedTokenError(reason error) *SignedTokenError {
+	return &SignedTokenError{Reason: reason}
+}
+
+// SignedToken holds the verified contents of a signed token
+type SignedToken struct {
+	// Nonce is a ULID unique to the token, which can be recorded to make tokens single use
+	Nonce string `json:"nonce"`
+	// KeyID identifies the key the token was signed with
+	KeyID string `json:"kid"`
+	// Purpose is what the token was issued for, such as email verification or password reset
+	Purpose string `json:"purpose"`
+	// IssuedAt is when the token was signed
+	IssuedAt time.Time `json:"-"`
+	// ExpiresAt is when the token expires
+	ExpiresAt time.Time `json:"-"`
+	// Claims are application values carried by the token, such as a user or invite ID
+	Claims map[string]any `json:"claims,omitempty"`
+}
+
+// signedPayload is the encoded form of a SignedToken
+type signedPayload struct {
+	SignedToken
+
+	IssuedAt  int64 `json:"iat"`
+	ExpiresAt int64 `json:"exp"`
+}
+
+// TokenSigner signs and verifies expiring, purpose bound tokens for links such as email verification,
+// invites and password resets. Tokens are URL safe and have the form
+//
+//	base64url(json payload).hex(hmac)
+//
+// The payload names the ID of its signing key, so keys can be rotated: tokens are signed with the
+// current key and verified with any key the signer holds. The payload is signed, not encrypted, and
+// should not carry secrets
+type TokenSigner struct {
+	alg     SigningAlgorithm
+	current string
+	keys    map[string]string
+	now     func() time.Time
+}
+
+// SignerOption configures a TokenSigner
+type SignerOption func(*TokenSigner)
+
+// WithSigningAlgorithm sets the HMAC used for tokens, SigningHS256 by default
+func WithSigningAlgorithm(alg SigningAlgorithm) SignerOption {
+	return func(s *TokenSigner) {
+		s.alg = alg
+	}
+}
+
+// WithSignerClock sets the function returning the current time, time.Now by default
+func WithSignerClock(now func() time.Time) SignerOption {
+	return func(s *TokenSigner) {
+		s.now = now
+	}
+}
+
+// NewTokenSigner returns a TokenSigner signing with the key of the current ID and verifying with any of
+// the keys
+func NewTokenSigner(current string, keys map[string][]byte, opts ...SignerOption) (*TokenSigner, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSigningKey, current)
+	}
+
+	s := &TokenSigner{alg: SigningHS256, current: current, keys: make(map[string]string, len(keys)), now: time.Now}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.alg != SigningHS256 && s.alg != SigningHS512 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSigningAlgorithm, s.alg)
+	}
+
+	for id, key := range keys {
+		if id == "" || len(key) < MinSigningKeyLength {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidSigningKey, id)
+		}
+
+		s.keys[id] = string(key)
+	}
+
+	return s, nil
+}
+
+// Sign returns a token for the purpose that expires after ttl and carries the claims
+func (s *TokenSigner) Sign(purpose string, ttl time.Duration, claims map[string]any) (string, error) {
+	now := s.now()
+
+	payload, err := json.Marshal(&signedPayload{
+		SignedToken: SignedToken{Nonce: ulids.New().String(), KeyID: s.current, Purpose: purpose, Claims: claims},
+		IssuedAt:    now.Unix(),
+		ExpiresAt:   now.Add(ttl).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+
+	return encoded + "." + s.sign(encoded, s.keys[s.current]), nil
+}
+
+// Verify checks the signature, purpose and expiry of a token and returns its contents; failures are
+// returned as a *SignedTokenError
+func (s *TokenSigner) Verify(token, purpose string) (*SignedToken, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, newSignedTokenError(ErrSignedTokenMalformed)
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, newSignedTokenError(ErrSignedTokenMalformed)
+	}
+
+	payload := &signedPayload{}
+	if err := json.Unmarshal(raw, payload); err != nil {
+		return nil, newSignedTokenError(ErrSignedTokenMalformed)
+	}
+
+	key, ok := s.keys[payload.KeyID]
+	if !ok {
+		return nil, newSignedTokenError(ErrUnknownSigningKey)
+	}
+
+	if !Equal(signature, s.sign(encoded, key)) {
+		return nil, newSignedTokenError(ErrSignedTokenSignature)
+	}
+
+	if payload.Purpose != purpose {
+		return nil, newSignedTokenError(ErrSignedTokenPurpose)
+	}
+
+	if !s.now().Before(time.Unix(payload.ExpiresAt, 0)) {
+		return nil, newSignedTokenError(ErrSignedTokenExpired)
+	}
+
+	t := payload.SignedToken
+	t.IssuedAt = time.Unix(payload.IssuedAt, 0)
+	t.ExpiresAt = time.Unix(payload.ExpiresAt, 0)
+
+	return &t, nil
+}
+
+// sign returns the hex encoded HMAC of the encoded payload
+func (s *TokenSigner) sign(encoded, key string) string {
+	if s.alg == SigningHS512 {
+		return HS512(encoded, key)
+	}
+
+	return HS256(encoded, key)
+}
@@ -0,0 +1,142 @@
+package keygen_test
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theopenlane/utils/keygen"
+	"github.com/theopenlane/utils/rout"
+	"github.com/theopenlane/utils/ulids"
+)
+
+var (
+	signingKeyA = bytes.Repeat([]byte("a"), keygen.MinSigningKeyLength)
+	signingKeyB = bytes.Repeat([]byte("b"), keygen.MinSigningKeyLength)
+)
+
+func TestTokenSigner(t *testing.T) {
+	for _, alg := range []keygen.SigningAlgorithm{keygen.SigningHS256, keygen.SigningHS512} {
+		t.Run(string(alg), func(t *testing.T) {
+			signer, err := keygen.NewTokenSigner("a", map[string][]byte{"a": signingKeyA}, keygen.WithSigningAlgorithm(alg))
+			require.NoError(t, err)
+
+			token, err := signer.Sign("verify_email", time.Hour, map[string]any{"email": "sfunk@theopenlane.io"})
+			require.NoError(t, err)
+			assert.Equal(t, url.QueryEscape(token), token, "tokens are URL safe")
+
+			verified, err := signer.Verify(token, "verify_email")
+			require.NoError(t, err)
+			assert.Equal(t, "a", verified.KeyID)
+			assert.Equal(t, "verify_email", verified.Purpose)
+			assert.Equal(t, "sfunk@theopenlane.io", verified.Claims["email"])
+			assert.WithinDuration(t, time.Now().Add(time.Hour), verified.ExpiresAt, 2*time.Second)
+			assert.WithinDuration(t, time.Now(), verified.IssuedAt, 2*time.Second)
+
+			_, err = ulids.Parse(verified.Nonce)
+			require.NoError(t, err)
+
+			other, err := signer.Sign("verify_email", time.Hour, nil)
+			require.NoError(t, err)
+			assert.NotEqual(t, token, other)
+		})
+	}
+}
+
+func TestTokenSignerErrors(t *testing.T) {
+	now := time.Now()
+	clock := func() time.Time { return now }
+
+	signer, err := keygen.NewTokenSigner("a", map[string][]byte{"a": signingKeyA}, keygen.WithSignerClock(clock))
+	require.NoError(t, err)
+
+	token, err := signer.Sign("reset_password", time.Minute, map[string]any{"user_id": "01J9"})
+	require.NoError(t, err)
+
+	payload, signature, _ := strings.Cut(token, ".")
+
+	forger, err := keygen.NewTokenSigner("a", map[string][]byte{"a": signingKeyB})
+	require.NoError(t, err)
+
+	forged, err := forger.Sign("reset_password", time.Minute, nil)
+	require.NoError(t, err)
+
+	rotated, err := keygen.NewTokenSigner("b", map[string][]byte{"b": signingKeyB})
+	require.NoError(t, err)
+
+	unknown, err := rotated.Sign("reset_password", time.Minute, nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		token   string
+		purpose string
+		reason  error
+		rout    error
+	}{
+		{name: "malformed", token: "nope", purpose: "reset_password", reason: keygen.ErrSignedTokenMalformed, rout: rout.ErrInvalidCredentials},
+		{name: "bad payload", token: "!!." + signature, purpose: "reset_password", reason: keygen.ErrSignedTokenMalformed, rout: rout.ErrInvalidCredentials},
+		{name: "bad signature", token: payload + "." + strings.Repeat("0", len(signature)), purpose: "reset_password", reason: keygen.ErrSignedTokenSignature, rout: rout.ErrInvalidCredentials},
+		{name: "other key", token: forged, purpose: "reset_password", reason: keygen.ErrSignedTokenSignature, rout: rout.ErrInvalidCredentials},
+		{name: "unknown key", token: unknown, purpose: "reset_password", reason: keygen.ErrUnknownSigningKey, rout: rout.ErrInvalidCredentials},
+		{name: "wrong purpose", token: token, purpose: "verify_email", reason: keygen.ErrSignedTokenPurpose, rout: rout.ErrInvalidCredentials},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := signer.Verify(tc.token, tc.purpose)
+
+			var tokenErr *keygen.SignedTokenError
+			require.ErrorAs(t, err, &tokenErr)
+			assert.ErrorIs(t, err, tc.reason)
+			assert.ErrorIs(t, err, tc.rout)
+		})
+	}
+
+	now = now.Add(time.Minute)
+
+	_, err = signer.Verify(token, "reset_password")
+	assert.ErrorIs(t, err, keygen.ErrSignedTokenExpired)
+	assert.ErrorIs(t, err, rout.ErrExpiredCredentials)
+	assert.NotErrorIs(t, err, rout.ErrInvalidCredentials)
+}
+
+func TestTokenSignerRotation(t *testing.T) {
+	old, err := keygen.NewTokenSigner("a", map[string][]byte{"a": signingKeyA})
+	require.NoError(t, err)
+
+	token, err := old.Sign("invite", time.Hour, nil)
+	require.NoError(t, err)
+
+	rotated, err := keygen.NewTokenSigner("b", map[string][]byte{"a": signingKeyA, "b": signingKeyB})
+	require.NoError(t, err)
+
+	_, err = rotated.Verify(token, "invite")
+	require.NoError(t, err)
+
+	fresh, err := rotated.Sign("invite", time.Hour, nil)
+	require.NoError(t, err)
+
+	verified, err := rotated.Verify(fresh, "invite")
+	require.NoError(t, err)
+	assert.Equal(t, "b", verified.KeyID)
+
+	_, err = old.Verify(fresh, "invite")
+	assert.ErrorIs(t, err, keygen.ErrUnknownSigningKey)
+}
+
+func TestNewTokenSigner(t *testing.T) {
+	_, err := keygen.NewTokenSigner("a", map[string][]byte{"b": signingKeyB})
+	assert.ErrorIs(t, err, keygen.ErrUnknownSigningKey)
+
+	_, err = keygen.NewTokenSigner("a", map[string][]byte{"a": []byte("short")})
+	assert.ErrorIs(t, err, keygen.ErrInvalidSigningKey)
+
+	_, err = keygen.NewTokenSigner("a", map[string][]byte{"a": signingKeyA}, keygen.WithSigningAlgorithm("RS256"))
+	assert.ErrorIs(t, err, keygen.ErrInvalidSigningAlgorithm)
+}
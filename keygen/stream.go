@@ -0,0 +1,328 @@
+package keygen
+
+import (
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Sizes of the streaming encryption format
+const (
+	// StreamChunkSize is the plaintext size of every chunk of a stream but the last
+	StreamChunkSize = 64 * 1024
+
+	// streamVersion is the version byte of streams created by NewEncryptWriter
+	streamVersion byte = 1
+	// streamSaltSize is the size of the random salt the chunk key is derived with
+	streamSaltSize = 16
+	// streamHeaderSize is the size of the version byte and salt
+	streamHeaderSize = 1 + streamSaltSize
+	// encryptedChunkSize is the size of a sealed full chunk
+	encryptedChunkSize = StreamChunkSize + gcmTagSize
+	// streamKeyInfo binds derived chunk keys to the stream format
+	streamKeyInfo = "keygen stream v1"
+)
+
+var (
+	// ErrStreamTruncated is returned when a stream ends before its final chunk
+	ErrStreamTruncated = errors.New("encrypted stream is truncated")
+	// ErrStreamClosed is returned when writing to a closed encrypting writer
+	ErrStreamClosed = errors.New("encrypted stream is closed")
+)
+
+// stream seals and opens the chunks of a stream in the STREAM construction used by age: each chunk has
+// the nonce counter (11 bytes) | last chunk flag (1 byte), so chunks cannot be reordered, dropped or
+// appended, and a stream cut at a chunk boundary is detected by its missing final chunk
+type stream struct {
+	aead    cipher.AEAD
+	ad      []byte
+	counter uint64
+	nonce   [gcmNonceSize]byte
+}
+
+// newStream derives the chunk key of a stream from the key and salt
+func newStream(key, salt, ad []byte) (*stream, error) {
+	if len(key) != KeySize {
+		return nil, ErrInvalidKeySize
+	}
+
+	chunkKey, err := hkdf.Key(sha256.New, key, salt, streamKeyInfo, KeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newGCM(chunkKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stream{aead: aead, ad: ad}, nil
+}
+
+// nextNonce returns the nonce of the next chunk
+func (s *stream) nextNonce(last bool) ([]byte, error) {
+	if s.counter == math.MaxUint64 {
+		return nil, ErrMalformedCiphertext
+	}
+
+	binary.BigEndian.PutUint64(s.nonce[3:11], s.counter)
+	s.nonce[11] = 0
+
+	if last {
+		s.nonce[11] = 1
+	}
+
+	return s.nonce[:], nil
+}
+
+// seal appends the next sealed chunk to dst
+func (s *stream) seal(dst, chunk []byte, last bool) ([]byte, error) {
+	nonce, err := s.nextNonce(last)
+	if err != nil {
+		return nil, err
+	}
+
+	s.counter++
+
+	return s.aead.Seal(dst, nonce, chunk, s.ad), nil
+}
+
+// open appends the plaintext of the next chunk to dst. A full chunk at the end of the stream that only
+// opens as a non-final chunk means the stream was cut at a chunk boundary
+func (s *stream) open(dst, chunk []byte, last bool) ([]byte, error) {
+	nonce, err := s.nextNonce(last)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := s.aead.Open(dst, nonce, chunk, s.ad)
+	if err != nil {
+		if last && len(chunk) == encryptedChunkSize {
+			nonce, _ = s.nextNonce(false)
+
+			if _, err := s.aead.Open(dst, nonce, chunk, s.ad); err == nil {
+				return nil, ErrStreamTruncated
+			}
+		}
+
+		return nil, ErrDecryptionFailed
+	}
+
+	s.counter++
+
+	return plaintext, nil
+}
+
+// EncryptWriter encrypts everything written to it as a stream of authenticated chunks
+type EncryptWriter struct {
+	w      io.Writer
+	stream *stream
+	buf    []byte
+	out    []byte
+	err    error
+}
+
+// NewEncryptWriter returns a writer encrypting to w with the KeySize key and associated data, which
+// must be passed to NewDecryptReader unchanged. The plaintext is split into chunks of
+// StreamChunkSize, so arbitrarily large exports and uploads are encrypted in constant memory
+//
+//	version (1) | salt (16) | chunk 0 | chunk 1 | ... | final chunk
+//
+// Close must be called to write the final chunk, otherwise the stream fails to decrypt as truncated
+func NewEncryptWriter(w io.Writer, key, ad []byte) (*EncryptWriter, error) {
+	salt, err := randomNonce(streamSaltSize)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := newStream(key, salt, ad)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(append([]byte{streamVersion}, salt...)); err != nil {
+		return nil, err
+	}
+
+	return &EncryptWriter{
+		w:      w,
+		stream: s,
+		buf:    make([]byte, 0, StreamChunkSize),
+		out:    make([]byte, 0, encryptedChunkSize),
+	}, nil
+}
+
+// Write encrypts p; full chunks are written as soon as it is known they are not the last one
+func (e *EncryptWriter) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+
+	n := 0
+
+	for len(p) > 0 {
+		if len(e.buf) == StreamChunkSize {
+			if err := e.flush(false); err != nil {
+				return n, err
+			}
+		}
+
+		c := copy(e.buf[len(e.buf):StreamChunkSize], p)
+		e.buf = e.buf[:len(e.buf)+c]
+		p = p[c:]
+		n += c
+	}
+
+	return n, nil
+}
+
+// Close writes the final chunk; it does not close the underlying writer
+func (e *EncryptWriter) Close() error {
+	if e.err != nil {
+		if errors.Is(e.err, ErrStreamClosed) {
+			return nil
+		}
+
+		return e.err
+	}
+
+	if err := e.flush(true); err != nil {
+		return err
+	}
+
+	e.err = ErrStreamClosed
+
+	return nil
+}
+
+// flush seals and writes the buffered chunk
+func (e *EncryptWriter) flush(last bool) error {
+	out, err := e.stream.seal(e.out[:0], e.buf, last)
+	if err != nil {
+		e.err = err
+
+		return err
+	}
+
+	e.out = out
+	e.buf = e.buf[:0]
+
+	if _, err := e.w.Write(e.out); err != nil {
+		e.err = err
+
+		return err
+	}
+
+	return nil
+}
+
+// DecryptReader decrypts a stream created by an EncryptWriter, only returning authenticated plaintext
+type DecryptReader struct {
+	r      io.Reader
+	stream *stream
+	// chunk holds an encrypted chunk and one byte of lookahead, used to tell if the chunk is the last
+	chunk     []byte
+	lookahead bool
+	buf       []byte
+	plaintext []byte
+	done      bool
+	err       error
+}
+
+// NewDecryptReader returns a reader decrypting the stream read from r with the key and associated data
+// it was encrypted with. Reads fail with ErrDecryptionFailed for tampered or cut off chunks and with
+// ErrStreamTruncated when the stream ends between chunks
+func NewDecryptReader(r io.Reader, key, ad []byte) (*DecryptReader, error) {
+	header := make([]byte, streamHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrStreamTruncated
+		}
+
+		return nil, err
+	}
+
+	if header[0] != streamVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedCiphertextVersion, header[0])
+	}
+
+	s, err := newStream(key, header[1:], ad)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DecryptReader{
+		r:      r,
+		stream: s,
+		chunk:  make([]byte, encryptedChunkSize+1),
+		buf:    make([]byte, 0, StreamChunkSize),
+	}, nil
+}
+
+// Read reads decrypted plaintext
+func (d *DecryptReader) Read(p []byte) (int, error) {
+	for len(d.plaintext) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+
+		if d.done {
+			return 0, io.EOF
+		}
+
+		if err := d.readChunk(); err != nil {
+			d.err = err
+		}
+	}
+
+	n := copy(p, d.plaintext)
+	d.plaintext = d.plaintext[n:]
+
+	return n, nil
+}
+
+// readChunk reads, authenticates and decrypts the next chunk
+func (d *DecryptReader) readChunk() error {
+	start := 0
+	if d.lookahead {
+		d.chunk[0] = d.chunk[encryptedChunkSize]
+		start = 1
+	}
+
+	n, err := io.ReadFull(d.r, d.chunk[start:])
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+
+	n += start
+
+	last := n <= encryptedChunkSize
+	if !last {
+		n = encryptedChunkSize
+	}
+
+	if n < gcmTagSize {
+		return ErrStreamTruncated
+	}
+
+	// only an empty stream has an empty final chunk
+	if last && n == gcmTagSize && d.stream.counter > 0 {
+		return ErrMalformedCiphertext
+	}
+
+	plaintext, err := d.stream.open(d.buf[:0], d.chunk[:n], last)
+	if err != nil {
+		return err
+	}
+
+	d.plaintext = plaintext
+	d.done = last
+	d.lookahead = !last
+
+	return nil
+}
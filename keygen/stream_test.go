@@ -0,0 +1,148 @@
+package keygen_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theopenlane/utils/keygen"
+)
+
+func encryptStream(t *testing.T, key, ad, plaintext []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	w, err := keygen.NewEncryptWriter(&buf, key, ad)
+	require.NoError(t, err)
+
+	// write in odd sized pieces so chunks are assembled from several writes
+	for p := plaintext; len(p) > 0; {
+		n := min(len(p), 1000)
+		_, err := w.Write(p[:n])
+		require.NoError(t, err)
+
+		p = p[n:]
+	}
+
+	require.NoError(t, w.Close())
+	require.NoError(t, w.Close())
+
+	_, err = w.Write([]byte("more"))
+	assert.ErrorIs(t, err, keygen.ErrStreamClosed)
+
+	return buf.Bytes()
+}
+
+func decryptStream(key, ad, ciphertext []byte) ([]byte, error) {
+	r, err := keygen.NewDecryptReader(iotest.HalfReader(bytes.NewReader(ciphertext)), key, ad)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(r)
+}
+
+func TestStream(t *testing.T) {
+	key := keygen.GenerateKey()
+	ad := []byte("export-01J9")
+
+	for _, size := range []int{0, 1, keygen.StreamChunkSize - 1, keygen.StreamChunkSize, keygen.StreamChunkSize + 1, 3*keygen.StreamChunkSize + 7} {
+		plaintext := keygen.GenerateRandomBytes(size)
+		ciphertext := encryptStream(t, key, ad, plaintext)
+
+		chunks := max(1, (size+keygen.StreamChunkSize-1)/keygen.StreamChunkSize)
+		assert.Len(t, ciphertext, 17+size+16*chunks, size)
+
+		decrypted, err := decryptStream(key, ad, ciphertext)
+		require.NoError(t, err, size)
+		assert.True(t, bytes.Equal(plaintext, decrypted), size)
+	}
+}
+
+func TestStreamTampering(t *testing.T) {
+	key := keygen.GenerateKey()
+	plaintext := keygen.GenerateRandomBytes(2*keygen.StreamChunkSize + 10)
+	ciphertext := encryptStream(t, key, nil, plaintext)
+	chunk := keygen.StreamChunkSize + 16
+
+	tests := []struct {
+		name       string
+		key        []byte
+		ad         []byte
+		ciphertext []byte
+		err        error
+	}{
+		{name: "wrong key", key: keygen.GenerateKey(), ciphertext: ciphertext, err: keygen.ErrDecryptionFailed},
+		{name: "wrong associated data", key: key, ad: []byte("other"), ciphertext: ciphertext, err: keygen.ErrDecryptionFailed},
+		{name: "truncated at chunk boundary", key: key, ciphertext: ciphertext[:17+2*chunk], err: keygen.ErrStreamTruncated},
+		{name: "truncated in chunk", key: key, ciphertext: ciphertext[:len(ciphertext)-1], err: keygen.ErrDecryptionFailed},
+		{name: "dropped chunk", key: key, ciphertext: append(append([]byte(nil), ciphertext[:17+chunk]...), ciphertext[17+2*chunk:]...), err: keygen.ErrDecryptionFailed},
+		{name: "appended data", key: key, ciphertext: append(append([]byte(nil), ciphertext...), 0), err: keygen.ErrDecryptionFailed},
+		{name: "flipped bit", key: key, ciphertext: flip(ciphertext, 17+chunk+5), err: keygen.ErrDecryptionFailed},
+		{name: "flipped salt", key: key, ciphertext: flip(ciphertext, 3), err: keygen.ErrDecryptionFailed},
+		{name: "header only", key: key, ciphertext: ciphertext[:17], err: keygen.ErrStreamTruncated},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			decrypted, err := decryptStream(tc.key, tc.ad, tc.ciphertext)
+			assert.ErrorIs(t, err, tc.err)
+			assert.True(t, bytes.HasPrefix(plaintext, decrypted), "only authenticated plaintext is returned")
+		})
+	}
+
+	_, err := keygen.NewDecryptReader(bytes.NewReader(ciphertext[:5]), key, nil)
+	assert.ErrorIs(t, err, keygen.ErrStreamTruncated)
+
+	_, err = keygen.NewDecryptReader(bytes.NewReader(flip(ciphertext, 0)), key, nil)
+	assert.ErrorIs(t, err, keygen.ErrUnsupportedCiphertextVersion)
+
+	_, err = keygen.NewEncryptWriter(io.Discard, []byte("short"), nil)
+	assert.ErrorIs(t, err, keygen.ErrInvalidKeySize)
+}
+
+func TestEncryptWithAD(t *testing.T) {
+	key := "abcdabcdabcdabcdabcdabcdabcdabcd"
+
+	ciphertext, err := keygen.EncryptWithAD([]byte("123"), key, []byte("record-1"))
+	require.NoError(t, err)
+
+	decrypted, err := keygen.DecryptWithAD(ciphertext, key, []byte("record-1"))
+	require.NoError(t, err)
+	assert.Equal(t, "123", string(decrypted))
+
+	_, err = keygen.DecryptWithAD(ciphertext, key, []byte("record-2"))
+	assert.Error(t, err)
+
+	_, err = keygen.Decrypt(ciphertext, key)
+	assert.Error(t, err)
+
+	_, err = keygen.Decrypt("YWJj", key)
+	assert.ErrorIs(t, err, keygen.ErrMalformedCiphertext)
+
+	ring, err := keygen.NewKeyring(1, map[uint32][]byte{1: keygen.GenerateKey()})
+	require.NoError(t, err)
+
+	sealed, err := ring.EncryptWithAD([]byte("123"), []byte("record-1"))
+	require.NoError(t, err)
+
+	decrypted, err = ring.DecryptWithAD(sealed, []byte("record-1"))
+	require.NoError(t, err)
+	assert.Equal(t, "123", string(decrypted))
+
+	_, err = ring.DecryptWithAD(sealed, []byte("record-2"))
+	assert.ErrorIs(t, err, keygen.ErrDecryptionFailed)
+}
+
+// flip returns a copy of b with one bit of the byte at i flipped
+func flip(b []byte, i int) []byte {
+	out := append([]byte(nil), b...)
+	out[i] ^= 1
+
+	return out
+}
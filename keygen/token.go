@@ -0,0 +1,191 @@
+package keygen
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Defaults for structured API tokens
+const (
+	// TokenVersion is the version of the token format generated by this package
+	TokenVersion = 1
+	// TokenBodyLength is the number of random base62 characters in a token, about 178 bits of entropy
+	TokenBodyLength = 30
+	// TokenChecksumLength is the number of base62 characters holding the CRC32 checksum of a token
+	TokenChecksumLength = 6
+	// MaxTokenPrefixLength is the longest prefix accepted by NewTokenFormat
+	MaxTokenPrefixLength = 16
+)
+
+// base62 is the alphabet checksums are encoded with, in ascending order so that they sort like numbers
+const base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+var (
+	// ErrInvalidTokenPrefix is returned for a prefix that is empty, too long or not lowercase alphanumeric
+	ErrInvalidTokenPrefix = errors.New("token prefix must be 1 to 16 lowercase letters, digits or underscores")
+	// ErrMalformedToken is returned when a token does not have the prefix_version_body form
+	ErrMalformedToken = errors.New("malformed token")
+	// ErrTokenPrefixMismatch is returned when a token was issued with a different prefix
+	ErrTokenPrefixMismatch = errors.New("token prefix does not match")
+	// ErrUnsupportedTokenVersion is returned for a token version this package cannot parse
+	ErrUnsupportedTokenVersion = errors.New("unsupported token version")
+	// ErrTokenChecksum is returned when the checksum of a token does not match, usually because of a typo
+	ErrTokenChecksum = errors.New("token checksum mismatch")
+)
+
+// tokenPrefix matches the prefixes accepted by NewTokenFormat
+var tokenPrefix = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9_]*[a-z0-9])?$`)
+
+// Token is a parsed structured API token of the form prefix_version_body, where the body is random
+// base62 text followed by the base62 CRC32 checksum of everything before it, for example
+//
+//	olt_1_Qm3Ff9kG0yVv7lTzR2bWcX8nH5pJ1a0Lr7Xe
+//
+// The checksum lets secret scanners and APIs reject mistyped or made up tokens without a database
+// lookup; it is not a signature and anyone can compute it
+type Token struct {
+	// Prefix identifies the kind of token, such as olt for API tokens
+	Prefix string
+	// Version is the token format version
+	Version int
+	// Body is the random part of the token
+	Body string
+	// Checksum is the encoded CRC32 checksum of the token
+	Checksum string
+}
+
+// String returns the token in its encoded form
+func (t *Token) String() string {
+	return t.payload() + t.Checksum
+}
+
+// payload returns the part of the token covered by the checksum
+func (t *Token) payload() string {
+	return t.Prefix + "_" + strconv.Itoa(t.Version) + "_" + t.Body
+}
+
+// TokenFormat generates and validates tokens with a fixed prefix
+type TokenFormat struct {
+	prefix string
+}
+
+// NewTokenFormat returns a TokenFormat for the prefix, which must be 1 to 16 lowercase letters, digits
+// or underscores and may not start or end with an underscore
+func NewTokenFormat(prefix string) (*TokenFormat, error) {
+	if len(prefix) > MaxTokenPrefixLength || !tokenPrefix.MatchString(prefix) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTokenPrefix, prefix)
+	}
+
+	return &TokenFormat{prefix: prefix}, nil
+}
+
+// Prefix returns the prefix of the tokens of the format
+func (f *TokenFormat) Prefix() string {
+	return f.prefix
+}
+
+// Pattern returns a regular expression matching tokens of the format, for use by secret scanners
+func (f *TokenFormat) Pattern() string {
+	return fmt.Sprintf(`\b%s_%d_[0-9A-Za-z]{%d}\b`, regexp.QuoteMeta(f.prefix), TokenVersion, TokenBodyLength+TokenChecksumLength)
+}
+
+// Generate returns a new random token
+func (f *TokenFormat) Generate() string {
+	token := &Token{Prefix: f.prefix, Version: TokenVersion, Body: AlphaNumeric(TokenBodyLength)}
+	token.Checksum = tokenChecksum(token.payload())
+
+	return token.String()
+}
+
+// Parse splits a token into its parts, checking its prefix, version and checksum
+func (f *TokenFormat) Parse(token string) (*Token, error) {
+	t, err := ParseToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Prefix != f.prefix {
+		return nil, fmt.Errorf("%w: expected %s, got %s", ErrTokenPrefixMismatch, f.prefix, t.Prefix)
+	}
+
+	return t, nil
+}
+
+// Validate checks the prefix, version and checksum of a token
+func (f *TokenFormat) Validate(token string) error {
+	_, err := f.Parse(token)
+
+	return err
+}
+
+// ParseToken splits a token of any prefix into its parts, checking its version and checksum
+func ParseToken(token string) (*Token, error) {
+	rest := strings.LastIndexByte(token, '_')
+	if rest < 0 || len(token)-rest-1 != TokenBodyLength+TokenChecksumLength {
+		return nil, ErrMalformedToken
+	}
+
+	sep := strings.LastIndexByte(token[:rest], '_')
+	if sep <= 0 {
+		return nil, ErrMalformedToken
+	}
+
+	t := &Token{
+		Prefix:   token[:sep],
+		Body:     token[rest+1 : len(token)-TokenChecksumLength],
+		Checksum: token[len(token)-TokenChecksumLength:],
+	}
+
+	if !tokenPrefix.MatchString(t.Prefix) || !isBase62(token[rest+1:]) {
+		return nil, ErrMalformedToken
+	}
+
+	// only the canonical form of a version is accepted, so 01 and +1 do not pass as version 1
+	segment := token[sep+1 : rest]
+
+	version, err := strconv.Atoi(segment)
+	if err != nil || strconv.Itoa(version) != segment {
+		return nil, ErrMalformedToken
+	}
+
+	if version != TokenVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedTokenVersion, version)
+	}
+
+	t.Version = version
+
+	// the checksum covers the token exactly as given rather than its rendered parts
+	if !Equal(t.Checksum, tokenChecksum(token[:len(token)-TokenChecksumLength])) {
+		return nil, ErrTokenChecksum
+	}
+
+	return t, nil
+}
+
+// tokenChecksum returns the CRC32 checksum of s encoded as fixed width base62
+func tokenChecksum(s string) string {
+	sum := crc32.ChecksumIEEE([]byte(s))
+	buf := []byte(strings.Repeat("0", TokenChecksumLength))
+
+	for i := TokenChecksumLength - 1; i >= 0 && sum > 0; i-- {
+		buf[i] = base62[sum%uint32(len(base62))]
+		sum /= uint32(len(base62))
+	}
+
+	return string(buf)
+}
+
+// isBase62 reports whether s only holds base62 characters
+func isBase62(s string) bool {
+	for i := range len(s) {
+		if !strings.ContainsRune(base62, rune(s[i])) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,86 @@
+package keygen_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theopenlane/utils/keygen"
+)
+
+func TestTokenFormat(t *testing.T) {
+	format, err := keygen.NewTokenFormat("olt")
+	require.NoError(t, err)
+
+	token := format.Generate()
+	assert.Regexp(t, regexp.MustCompile(format.Pattern()), token)
+	assert.Len(t, token, len("olt_1_")+keygen.TokenBodyLength+keygen.TokenChecksumLength)
+	assert.NotEqual(t, token, format.Generate())
+
+	parsed, err := format.Parse(token)
+	require.NoError(t, err)
+	assert.Equal(t, "olt", parsed.Prefix)
+	assert.Equal(t, keygen.TokenVersion, parsed.Version)
+	assert.Len(t, parsed.Body, keygen.TokenBodyLength)
+	assert.Len(t, parsed.Checksum, keygen.TokenChecksumLength)
+	assert.Equal(t, token, parsed.String())
+
+	assert.NoError(t, format.Validate(token))
+
+	other, err := keygen.NewTokenFormat("olt_svc")
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, other.Validate(token), keygen.ErrTokenPrefixMismatch)
+
+	parsed, err = keygen.ParseToken(other.Generate())
+	require.NoError(t, err)
+	assert.Equal(t, "olt_svc", parsed.Prefix)
+}
+
+func TestTokenValidation(t *testing.T) {
+	format, err := keygen.NewTokenFormat("olt")
+	require.NoError(t, err)
+
+	token := format.Generate()
+
+	// change a single character of the body
+	typo := []byte(token)
+	if typo[10] == 'a' {
+		typo[10] = 'b'
+	} else {
+		typo[10] = 'a'
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{name: "empty", token: "", err: keygen.ErrMalformedToken},
+		{name: "legacy secret", token: keygen.PrefixedSecret("olt"), err: keygen.ErrMalformedToken},
+		{name: "no version", token: "olt_" + token[6:], err: keygen.ErrMalformedToken},
+		{name: "bad version", token: "olt_x_" + token[6:], err: keygen.ErrMalformedToken},
+		{name: "future version", token: "olt_2_" + token[6:], err: keygen.ErrUnsupportedTokenVersion},
+		{name: "padded version", token: "olt_01_" + token[6:], err: keygen.ErrMalformedToken},
+		{name: "signed version", token: "olt_+1_" + token[6:], err: keygen.ErrMalformedToken},
+		{name: "short", token: token[:len(token)-1], err: keygen.ErrMalformedToken},
+		{name: "symbols", token: token[:len(token)-1] + "-", err: keygen.ErrMalformedToken},
+		{name: "typo", token: string(typo), err: keygen.ErrTokenChecksum},
+		{name: "changed prefix", token: "olx" + token[3:], err: keygen.ErrTokenChecksum},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.ErrorIs(t, format.Validate(tc.token), tc.err)
+		})
+	}
+}
+
+func TestNewTokenFormat(t *testing.T) {
+	for _, prefix := range []string{"", "OLT", "_olt", "olt_", "olt-1", "averyveryverylongprefix"} {
+		_, err := keygen.NewTokenFormat(prefix)
+		assert.ErrorIs(t, err, keygen.ErrInvalidTokenPrefix, prefix)
+	}
+}
@@ -1,8 +1,8 @@
 package passwd
 
 import (
-	"bytes"
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/base64"
 	"fmt"
 	"regexp"
@@ -62,7 +62,7 @@ func VerifyDerivedKey(dk, password string) (bool, error) {
 
 	vdk := argon2.IDKey([]byte(password), salt, t, m, p, uint32(len(dkb))) // nolint:gosec
 
-	return bytes.Equal(dkb, vdk), nil
+	return subtle.ConstantTimeCompare(dkb, vdk) == 1, nil
 }
 
 // ParseDerivedKey returns the parts of the encoded derived key string.
@@ -122,6 +122,18 @@ func ParseDerivedKey(encoded string) (dk, salt []byte, time, memory uint32, thre
 	return dk, salt, time, memory, threads, nil
 }
 
+// IsDerivedKey reports whether s looks like an encoded derived key
 func IsDerivedKey(s string) bool {
 	return dkParse.MatchString(s)
 }
+
+// DerivedKeyNeedsRehash reports whether the derived key was created with parameters other than the
+// current ones, in which case it should be recreated the next time the password is verified
+func DerivedKeyNeedsRehash(encoded string) bool {
+	dk, salt, t, m, p, err := ParseDerivedKey(encoded)
+	if err != nil {
+		return true
+	}
+
+	return t != dkTime || m != dkMem || p != dkProc || len(salt) != dkSLen || len(dk) != int(dkKLen)
+}
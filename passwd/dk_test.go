@@ -43,6 +43,17 @@ func TestDerivedKey(t *testing.T) {
 	}
 }
 
+func TestDerivedKeyNeedsRehash(t *testing.T) {
+	derivedKey, err := passwd.CreateDerivedKey("supersecretpassword")
+	require.NoError(t, err)
+	require.False(t, passwd.DerivedKeyNeedsRehash(derivedKey))
+
+	// created with t=1 and p=4
+	require.True(t, passwd.DerivedKeyNeedsRehash("$argon2id$v=19$m=65536,t=1,p=4$FrAEw4rWRDpyIZXR/QSzpg==$chQikgApfQfSaPZ7idk6caqBk79xRalpPUs4Ro/hywM="))
+	require.True(t, passwd.DerivedKeyNeedsRehash("notarealkey"))
+	require.False(t, passwd.DerivedKeyNeedsRehash("$argon2id$v=19$m=65536,t=1,p=2$FrAEw4rWRDpyIZXR/QSzpg==$chQikgApfQfSaPZ7idk6caqBk79xRalpPUs4Ro/hywM="))
+}
+
 func TestDerivedKeyErrors(t *testing.T) {
 	testCases := []struct {
 		name          string